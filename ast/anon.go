@@ -0,0 +1,44 @@
+package ast
+
+import "llvm-lang/token"
+
+// AnonExprName is the name given to the synthesized function wrapping a top-level expression
+const AnonExprName = "__anon_expr"
+
+// WrapAnonExpr turns a top-level expression statement into `def __anon_expr() { expr }`, so a REPL or JIT
+// can compile it like any other function, call it once and throw it away. Named function definitions are
+// declarations rather than expressions to run, so they come back unchanged along with ok == false.
+func WrapAnonExpr(stmt *ExpressionStmt) (fn *FunctionLiteral, ok bool) {
+	if def, isDef := stmt.Expr.(*FunctionLiteral); isDef && def.Name != nil {
+		return def, false
+	}
+
+	pos := stmt.Token.Pos
+	return &FunctionLiteral{
+		Token:      token.Token{Type: token.Def, Literal: "def", Pos: pos},
+		Name:       &Identifier{Token: token.Token{Type: token.Identifier, Literal: AnonExprName, Pos: pos}, Value: AnonExprName},
+		Parameters: []*Param{},
+		Body:       &BlockStmt{Token: token.Token{Type: token.LeftCurlyBracket, Literal: "{", Pos: pos}, Stmts: []Stmt{stmt}},
+	}, true
+}
+
+// WrapAnonExprs applies WrapAnonExpr to every top-level statement of program, leaving declarations in place.
+// Each wrapped expression keeps its position in the statement list so they can be run in source order.
+func WrapAnonExprs(program *Program) *Program {
+	wrapped := &Program{Stmts: make([]Stmt, 0, len(program.Stmts))}
+
+	for _, stmt := range program.Stmts {
+		exprStmt, isExpr := stmt.(*ExpressionStmt)
+		if !isExpr || exprStmt.Expr == nil {
+			wrapped.Stmts = append(wrapped.Stmts, stmt)
+			continue
+		}
+
+		if fn, ok := WrapAnonExpr(exprStmt); ok {
+			stmt = &ExpressionStmt{Token: fn.Token, Expr: fn}
+		}
+		wrapped.Stmts = append(wrapped.Stmts, stmt)
+	}
+
+	return wrapped
+}
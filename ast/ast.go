@@ -2,6 +2,9 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
+	"go/constant"
+	"llvm-lang/numfmt"
 	"llvm-lang/token"
 	"strings"
 )
@@ -38,14 +41,158 @@ type (
 		Token token.Token
 		Expr  Expr
 	}
+
+	BlockStmt struct {
+		Token token.Token // token.LeftCurlyBracket
+		Stmts []Stmt
+	}
+
+	StructDecl struct {
+		Token      token.Token // token.Struct
+		Name       *Identifier
+		Fields     []*FieldDecl
+		Doc        string       // see FunctionLiteral.Doc
+		Attributes []*Attribute // see FunctionLiteral.Attributes
+	}
+
+	EnumDecl struct {
+		Token    token.Token // token.Enum
+		Name     *Identifier
+		Variants []*EnumVariant
+		Doc      string // see FunctionLiteral.Doc
+	}
+
+	// let n: int = 3; always introduces a new variable, the type is optional
+	LetStmt struct {
+		Token token.Token // token.Let
+		Name  *Identifier
+		Type  *Identifier // nil when not annotated
+		Value Expr
+	}
+
+	// const TAU = 2 * 3.14159; the value must fold to a constant at compile time
+	ConstDecl struct {
+		Token token.Token // token.Const
+		Name  *Identifier
+		Value Expr
+	}
+
+	// for i in 0..10 { ... }, i is scoped to the body
+	ForStmt struct {
+		Token    token.Token // token.For
+		Var      *Identifier
+		Iterable Expr
+		Body     *BlockStmt
+	}
+
+	// switch (x) { case 1, 2: ...; default: ... }, a case runs on its own without falling through to the next
+	SwitchStmt struct {
+		Token   token.Token // token.Switch
+		Subject Expr
+		Cases   []*SwitchCase
+	}
+
+	// test "adds" { ... }, test is only a keyword at the start of a statement followed by a string
+	TestDecl struct {
+		Token token.Token // the identifier test
+		Name  *StringLiteral
+		Body  *BlockStmt
+	}
+
+	// static_assert(N > 0, "N must be positive"); checked when constants are folded
+	StaticAssert struct {
+		Token     token.Token // token.StaticAssert
+		Condition Expr
+		Message   Expr // nil when not given
+	}
+)
+
+// Pieces of other nodes, not nodes themselves
+type (
+	FieldDecl struct {
+		Name *Identifier
+		Type *Identifier
+	}
+
+	// Payload lists the types a variant carries, empty for plain variants like Red
+	EnumVariant struct {
+		Name    *Identifier
+		Payload []*Identifier
+	}
+
+	// Values is nil for default, Body runs from the colon to the next case
+	SwitchCase struct {
+		Token  token.Token // token.Case or token.Default
+		Values []Expr
+		Body   *BlockStmt
+	}
+
+	// @name or @name(args) before a declaration, what each one means is up to package attribute
+	Attribute struct {
+		Token token.Token // token.At
+		Name  *Identifier
+		Args  []Expr // nil when written without parentheses
+	}
+
+	// Type is nil when the parameter isn't annotated
+	Param struct {
+		Name *Identifier
+		Type *Identifier
+	}
+
+	FieldValue struct {
+		Name  *Identifier
+		Value Expr
+	}
+
+	// Pattern is a number literal, a binding identifier, or _ which matches anything without binding
+	MatchArm struct {
+		Pattern Expr
+		Body    *BlockStmt
+	}
+
+	// A // comment, Text includes the slashes. Comments starting /// document the declaration below them.
+	Comment struct {
+		Pos  token.Position
+		Text string
+	}
 )
 
 // Expressions and literals
 type (
 	// Literals
+	// Value is exact, the token keeps the spelling the user wrote
 	NumberLiteral struct {
 		Token token.Token
-		Value float64
+		Value constant.Value
+	}
+
+	// Value is the text between the quotes or backticks
+	StringLiteral struct {
+		Token token.Token // token.String or token.RawString
+		Value string
+	}
+
+	// b"\x00\x01" or a <<<TAG heredoc, an array of bytes rather than a string. Value has its escapes decoded.
+	ByteLiteral struct {
+		Token token.Token // token.Bytes or token.Heredoc
+		Value []byte
+	}
+
+	// "x is ${x + 1}", Parts alternates between *StringLiteral text and the interpolated expressions
+	InterpolatedString struct {
+		Token token.Token // token.String
+		Parts []Expr
+	}
+
+	// The absence of a value, it has its own type rather than fitting wherever a number or string does
+	NilLiteral struct {
+		Token token.Token // token.Nil
+	}
+
+	BooleanLiteral struct {
+		Token token.Token // token.True or token.False
+		Value bool
 	}
 
 	// Expressions
@@ -56,22 +203,110 @@ type (
 
 	PrefixExpr struct {
 		Token    token.Token
-		Operator string
+		Operator Operator
 		Right    Expr
 	}
 
+	// x++ and x--, which update x like their prefix forms but are worth x as it was before. Token is the operator.
+	PostfixExpr struct {
+		Token    token.Token
+		Left     Expr
+		Operator Operator
+	}
+
+	// Left is evaluated before Right, which && and || skip when Left already decides the result
 	InfixExpr struct {
 		Token    token.Token
 		Left     Expr
-		Operator string
+		Operator Operator
 		Right    Expr
 	}
 
+	// Covers plain and compound assignment, Operator is OpAssign, OpPlusAssign, etc.
+	AssignExpr struct {
+		Token    token.Token
+		Target   Expr
+		Operator Operator
+		Value    Expr
+	}
+
+	// (a; b), evaluates each expression in turn for its effects and takes the value of the last
+	SequenceExpr struct {
+		Token token.Token // token.LeftParen
+		Exprs []Expr
+	}
+
+	// cond ? a : b, only one of the branches is evaluated
+	ConditionalExpr struct {
+		Token       token.Token // token.Question
+		Condition   Expr
+		Consequence Expr
+		Alternative Expr
+	}
+
+	// 0..10 counts up to 10, 0..=10 includes it
+	RangeExpr struct {
+		Token     token.Token // token.Range or token.RangeInclusive
+		Start     Expr
+		End       Expr
+		Inclusive bool
+	}
+
+	// x as int, an explicit conversion
+	CastExpr struct {
+		Token token.Token // token.As
+		Value Expr
+		Type  *Identifier
+	}
+
+	MatchExpr struct {
+		Token   token.Token // token.Match
+		Subject Expr
+		Arms    []*MatchArm
+	}
+
+	// try { ... } catch (e) { ... }, Err is nil when the handler doesn't name the error
+	TryExpr struct {
+		Token   token.Token // token.Try
+		Body    *BlockStmt
+		Err     *Identifier
+		Handler *BlockStmt
+	}
+
+	// Function is evaluated first, then Arguments from left to right
 	CallExpr struct {
 		Token     token.Token
 		Function  Expr
 		Arguments []Expr
 	}
+
+	StructLiteral struct {
+		Token  token.Token // token.LeftCurlyBracket
+		Type   *Identifier
+		Fields []*FieldValue
+	}
+
+	FieldAccessExpr struct {
+		Token  token.Token // token.Dot
+		Object Expr
+		Field  *Identifier
+	}
+
+	// Functions are values, so a definition is just a literal that may carry a name
+	FunctionLiteral struct {
+		Token      token.Token   // token.Def
+		Name       *Identifier   // nil for anonymous functions
+		TypeParams []*Identifier // the T in def id<T>(x: T): T
+		Parameters []*Param
+		Variadic   bool        // the last parameter, written xs..., collects any extra arguments
+		ReturnType *Identifier // nil when not annotated
+		Body       *BlockStmt
+		Pure       bool // declared with pure def, promising calls have no effects and depend only on their arguments
+		Attributes []*Attribute
+
+		// the /// comment lines directly above the definition, without the slashes, empty when there are none
+		Doc string
+	}
 )
 
 // Node interfaces
@@ -87,6 +322,42 @@ func (e *ExpressionStmt) TokenLiteral() string {
 	return e.Token.Literal
 }
 
+func (b *BlockStmt) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (s *StructDecl) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (e *EnumDecl) TokenLiteral() string {
+	return e.Token.Literal
+}
+
+func (l *LetStmt) TokenLiteral() string {
+	return l.Token.Literal
+}
+
+func (t *TestDecl) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (s *SwitchStmt) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (f *ForStmt) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (s *StaticAssert) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (c *ConstDecl) TokenLiteral() string {
+	return c.Token.Literal
+}
+
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
@@ -95,18 +366,90 @@ func (i *NumberLiteral) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (s *StringLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (b *ByteLiteral) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (i *InterpolatedString) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (n *NilLiteral) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (b *BooleanLiteral) TokenLiteral() string {
+	return b.Token.Literal
+}
+
 func (p *PrefixExpr) TokenLiteral() string {
 	return p.Token.Literal
 }
 
+func (p *PostfixExpr) TokenLiteral() string {
+	return p.Token.Literal
+}
+
 func (i *InfixExpr) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (a *AssignExpr) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (s *SequenceExpr) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (c *ConditionalExpr) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (r *RangeExpr) TokenLiteral() string {
+	return r.Token.Literal
+}
+
+func (c *CastExpr) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (t *TryExpr) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (m *MatchExpr) TokenLiteral() string {
+	return m.Token.Literal
+}
+
 func (c *CallExpr) TokenLiteral() string {
 	return c.Token.Literal
 }
 
+func (s *StructLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (f *FieldAccessExpr) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FunctionLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+// str prints a child that can be missing after a parse error, so String never panics on a partial tree
+func str(node Node) string {
+	if node == nil {
+		return ""
+	}
+	return node.String()
+}
+
 // Statements
 func (p *Program) String() string {
 	var out bytes.Buffer
@@ -125,6 +468,113 @@ func (e *ExpressionStmt) String() string {
 	return ""
 }
 
+func (b *BlockStmt) String() string {
+	var out bytes.Buffer
+
+	for _, stmt := range b.Stmts {
+		out.WriteString(stmt.String())
+	}
+
+	return out.String()
+}
+
+func (s *StructDecl) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(attributes(s.Attributes))
+	out.WriteString("struct ")
+	out.WriteString(s.Name.String())
+	out.WriteString(" { ")
+	for _, field := range s.Fields {
+		out.WriteString(field.Name.String() + ": " + field.Type.String() + "; ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (e *EnumDecl) String() string {
+	var out bytes.Buffer
+	variants := make([]string, 0)
+	for _, variant := range e.Variants {
+		variants = append(variants, variant.String())
+	}
+
+	out.WriteString("enum ")
+	out.WriteString(e.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(variants, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (l *LetStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("let " + l.Name.String())
+	if l.Type != nil {
+		out.WriteString(": " + l.Type.String())
+	}
+	out.WriteString(" = " + str(l.Value) + ";")
+
+	return out.String()
+}
+
+func (c *ConstDecl) String() string {
+	return "const " + c.Name.String() + " = " + str(c.Value) + ";"
+}
+
+func (t *TestDecl) String() string {
+	return "test " + t.Name.String() + " { " + t.Body.String() + " }"
+}
+
+func (s *SwitchStmt) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("switch (" + str(s.Subject) + ") { ")
+	for _, c := range s.Cases {
+		out.WriteString(c.String() + " ")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (c *SwitchCase) String() string {
+	if c.Values == nil {
+		return "default: " + c.Body.String()
+	}
+	values := make([]string, 0)
+	for _, value := range c.Values {
+		values = append(values, str(value))
+	}
+	return "case " + strings.Join(values, ", ") + ": " + c.Body.String()
+}
+
+func (f *ForStmt) String() string {
+	return "for " + f.Var.String() + " in " + str(f.Iterable) + " { " + f.Body.String() + " }"
+}
+
+func (s *StaticAssert) String() string {
+	if s.Message == nil {
+		return "static_assert(" + str(s.Condition) + ");"
+	}
+	return "static_assert(" + str(s.Condition) + ", " + str(s.Message) + ");"
+}
+
+func (v *EnumVariant) String() string {
+	if len(v.Payload) == 0 {
+		return v.Name.String()
+	}
+
+	types := make([]string, 0)
+	for _, t := range v.Payload {
+		types = append(types, t.String())
+	}
+	return v.Name.String() + "(" + strings.Join(types, ", ") + ")"
+}
+
 // Expressions
 func (i *Identifier) String() string {
 	return i.Value
@@ -134,33 +584,114 @@ func (p *PrefixExpr) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
-	out.WriteString(p.Operator)
-	out.WriteString(p.Right.String())
+	out.WriteString(p.Token.Literal)
+	out.WriteString(str(p.Right))
 	out.WriteString(")")
 
 	return out.String()
 }
 
+func (p *PostfixExpr) String() string {
+	return "(" + str(p.Left) + p.Token.Literal + ")"
+}
+
 func (i *InfixExpr) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("(")
-	out.WriteString(i.Left.String())
-	out.WriteString(" " + i.Operator + " ")
-	out.WriteString(i.Right.String())
+	out.WriteString(str(i.Left))
+	out.WriteString(" " + i.Token.Literal + " ")
+	out.WriteString(str(i.Right))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+func (a *AssignExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(str(a.Target))
+	out.WriteString(" " + a.Token.Literal + " ")
+	out.WriteString(str(a.Value))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+func (s *SequenceExpr) String() string {
+	exprs := make([]string, 0, len(s.Exprs))
+	for _, expr := range s.Exprs {
+		exprs = append(exprs, str(expr))
+	}
+	return "(" + strings.Join(exprs, "; ") + ")"
+}
+
+func (c *ConditionalExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(str(c.Condition))
+	out.WriteString(" ? ")
+	out.WriteString(str(c.Consequence))
+	out.WriteString(" : ")
+	out.WriteString(str(c.Alternative))
 	out.WriteString(")")
 
 	return out.String()
 }
 
+func (r *RangeExpr) String() string {
+	if r.Inclusive {
+		return "(" + str(r.Start) + "..=" + str(r.End) + ")"
+	}
+	return "(" + str(r.Start) + ".." + str(r.End) + ")"
+}
+
+func (c *CastExpr) String() string {
+	return "(" + str(c.Value) + " as " + c.Type.String() + ")"
+}
+
+func (m *MatchExpr) String() string {
+	var out bytes.Buffer
+	arms := make([]string, 0)
+	for _, arm := range m.Arms {
+		arms = append(arms, str(arm.Pattern)+" => { "+arm.Body.String()+" }")
+	}
+
+	out.WriteString("match ")
+	out.WriteString(str(m.Subject))
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (t *TryExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try { ")
+	out.WriteString(t.Body.String())
+	out.WriteString(" } catch ")
+	if t.Err != nil {
+		out.WriteString("(" + t.Err.String() + ") ")
+	}
+	out.WriteString("{ ")
+	out.WriteString(t.Handler.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
 func (c *CallExpr) String() string {
 	var out bytes.Buffer
 	args := make([]string, 0)
 	for _, arg := range c.Arguments {
-		args = append(args, arg.String())
+		args = append(args, str(arg))
 	}
 
-	out.WriteString(c.Function.String())
+	out.WriteString(str(c.Function))
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
@@ -168,17 +699,204 @@ func (c *CallExpr) String() string {
 	return out.String()
 }
 
+func (s *StructLiteral) String() string {
+	var out bytes.Buffer
+	fields := make([]string, 0)
+	for _, field := range s.Fields {
+		fields = append(fields, field.Name.String()+": "+str(field.Value))
+	}
+
+	out.WriteString(s.Type.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (f *FieldAccessExpr) String() string {
+	return str(f.Object) + "." + f.Field.String()
+}
+
+func (f *FunctionLiteral) String() string {
+	var out bytes.Buffer
+
+	if f.Token.Type == token.Backslash {
+		out.WriteString(f.TokenLiteral())
+		out.WriteString(strings.Join(f.params(), ", "))
+		out.WriteString(" -> { ")
+		out.WriteString(f.Body.String())
+		out.WriteString(" }")
+		return out.String()
+	}
+
+	out.WriteString(f.Signature())
+	out.WriteString(" { ")
+	out.WriteString(f.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// Signature prints everything before the body, like @inline pure def id<T>(x: T): T
+func (f *FunctionLiteral) Signature() string {
+	var out bytes.Buffer
+
+	out.WriteString(attributes(f.Attributes))
+	if f.Pure {
+		out.WriteString("pure ")
+	}
+	out.WriteString(f.TokenLiteral())
+	if f.Name != nil {
+		out.WriteString(" " + f.Name.String())
+	}
+	if len(f.TypeParams) > 0 {
+		typeParams := make([]string, 0)
+		for _, t := range f.TypeParams {
+			typeParams = append(typeParams, t.String())
+		}
+		out.WriteString("<" + strings.Join(typeParams, ", ") + ">")
+	}
+	out.WriteString("(")
+	out.WriteString(strings.Join(f.params(), ", "))
+	out.WriteString(")")
+	if f.ReturnType != nil {
+		out.WriteString(": " + f.ReturnType.String())
+	}
+
+	return out.String()
+}
+
+func (f *FunctionLiteral) params() []string {
+	params := make([]string, 0)
+	for i, param := range f.Parameters {
+		spelled := param.Name.String()
+		if f.Variadic && i == len(f.Parameters)-1 {
+			spelled += "..."
+		}
+		if param.Type != nil {
+			spelled += ": " + param.Type.String()
+		}
+		params = append(params, spelled)
+	}
+	return params
+}
+
+func (a *Attribute) String() string {
+	if a.Args == nil {
+		return "@" + a.Name.String()
+	}
+	args := make([]string, 0)
+	for _, arg := range a.Args {
+		args = append(args, arg.String())
+	}
+	return "@" + a.Name.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// each attribute followed by a space, ready to go in front of a declaration
+func attributes(attrs []*Attribute) string {
+	var out bytes.Buffer
+	for _, attr := range attrs {
+		out.WriteString(attr.String() + " ")
+	}
+	return out.String()
+}
+
 // Literals
+// prints the canonical value, so 1e2, 100.0 and 0x64 all print as 100
 func (i *NumberLiteral) String() string {
-	return i.Token.Literal
+	return numfmt.Constant(i.Value)
+}
+
+func (s *StringLiteral) String() string {
+	if s.Token.Type == token.RawString {
+		return "`" + s.Value + "`"
+	}
+	return `"` + s.Value + `"`
+}
+
+// heredocs print as byte strings too, escaping everything but printable ASCII
+func (b *ByteLiteral) String() string {
+	var out strings.Builder
+
+	out.WriteString(`b"`)
+	for _, c := range b.Value {
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c == '\n':
+			out.WriteString(`\n`)
+		case c == '\t':
+			out.WriteString(`\t`)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&out, `\x%02x`, c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteString(`"`)
+	return out.String()
+}
+
+func (i *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(`"`)
+	for _, part := range i.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(text.Value)
+		} else {
+			out.WriteString("${" + str(part) + "}")
+		}
+	}
+	out.WriteString(`"`)
+
+	return out.String()
+}
+
+func (n *NilLiteral) String() string {
+	return "nil"
+}
+
+func (b *BooleanLiteral) String() string {
+	if b.Value {
+		return "true"
+	}
+	return "false"
 }
 
 // Statements
 func (e *ExpressionStmt) statementNode() {}
+func (b *BlockStmt) statementNode()      {}
+func (s *StructDecl) statementNode()     {}
+func (e *EnumDecl) statementNode()       {}
+func (c *ConstDecl) statementNode()      {}
+func (s *StaticAssert) statementNode()   {}
+func (f *ForStmt) statementNode()        {}
+func (s *SwitchStmt) statementNode()     {}
+func (t *TestDecl) statementNode()       {}
+func (l *LetStmt) statementNode()        {}
 
 // Expressions
-func (i *Identifier) expressionNode()    {}
-func (n *NumberLiteral) expressionNode() {}
-func (p *PrefixExpr) expressionNode()    {}
-func (i *InfixExpr) expressionNode()     {}
-func (c *CallExpr) expressionNode()      {}
+func (i *Identifier) expressionNode()         {}
+func (n *NumberLiteral) expressionNode()      {}
+func (s *StringLiteral) expressionNode()      {}
+func (b *ByteLiteral) expressionNode()        {}
+func (i *InterpolatedString) expressionNode() {}
+func (n *NilLiteral) expressionNode()         {}
+func (b *BooleanLiteral) expressionNode()     {}
+func (p *PrefixExpr) expressionNode()         {}
+func (p *PostfixExpr) expressionNode()        {}
+func (i *InfixExpr) expressionNode()          {}
+func (a *AssignExpr) expressionNode()         {}
+func (s *SequenceExpr) expressionNode()       {}
+func (c *ConditionalExpr) expressionNode()    {}
+func (r *RangeExpr) expressionNode()          {}
+func (c *CastExpr) expressionNode()           {}
+func (m *MatchExpr) expressionNode()          {}
+func (t *TryExpr) expressionNode()            {}
+func (c *CallExpr) expressionNode()           {}
+func (s *StructLiteral) expressionNode()      {}
+func (f *FieldAccessExpr) expressionNode()    {}
+func (f *FunctionLiteral) expressionNode()    {}
@@ -0,0 +1,45 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Dot renders the tree rooted at node as a Graphviz digraph, one box per node
+// labelled with its kind and, where it has one, its literal or operator
+func Dot(node Node) string {
+	var out bytes.Buffer
+	id := 0
+
+	var visit func(n Node) int
+	visit = func(n Node) int {
+		self := id
+		id++
+		out.WriteString(fmt.Sprintf("  n%d [label=\"%s\"];\n", self, escapeDot(dotLabel(n))))
+		for _, child := range Children(n) {
+			out.WriteString(fmt.Sprintf("  n%d -> n%d;\n", self, visit(child)))
+		}
+		return self
+	}
+
+	out.WriteString("digraph AST {\n")
+	out.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	visit(node)
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+func dotLabel(node Node) string {
+	if detail := nodeDetail(node); detail != "" {
+		return nodeKind(node) + "\n" + detail
+	}
+	return nodeKind(node)
+}
+
+func escapeDot(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
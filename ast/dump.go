@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"llvm-lang/token"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Dump renders the tree rooted at node one node per line, children indented under their parent. Each line
+// has the node's kind, its literal or operator where it has one, and the line:column of its token. The
+// filename is left out so the output doesn't depend on where the source was read from.
+func Dump(node Node) string {
+	var out bytes.Buffer
+
+	var visit func(n Node, depth int)
+	visit = func(n Node, depth int) {
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString(nodeKind(n))
+		if detail := nodeDetail(n); detail != "" {
+			out.WriteString(" " + detail)
+		}
+		if pos, ok := position(n); ok {
+			out.WriteString(fmt.Sprintf(" %d:%d", pos.Line, pos.Column))
+		}
+		out.WriteString("\n")
+
+		for _, child := range Children(n) {
+			visit(child, depth+1)
+		}
+	}
+	visit(node, 0)
+
+	return out.String()
+}
+
+func nodeKind(node Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
+
+// the literal or operator that tells apart nodes of the same kind, empty for the rest
+func nodeDetail(node Node) string {
+	switch n := node.(type) {
+	case *Identifier:
+		return n.Value
+	case *NumberLiteral:
+		return n.Token.Literal
+	case *BooleanLiteral:
+		return n.String()
+	case *StringLiteral:
+		return strconv.Quote(n.Value)
+	case *ByteLiteral:
+		return n.String()
+	case *PrefixExpr:
+		return n.Operator.String()
+	case *PostfixExpr:
+		return n.Operator.String()
+	case *InfixExpr:
+		return n.Operator.String()
+	case *AssignExpr:
+		return n.Operator.String()
+	case *RangeExpr:
+		return n.Token.Literal
+	}
+	return ""
+}
+
+// every node but Program keeps the token it starts at in a Token field
+func position(node Node) (token.Position, bool) {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return token.Position{}, false
+	}
+	field := v.Elem().FieldByName("Token")
+	if !field.IsValid() {
+		return token.Position{}, false
+	}
+	tok, ok := field.Interface().(token.Token)
+	return tok.Pos, ok
+}
@@ -0,0 +1,113 @@
+package ast
+
+import (
+	"fmt"
+	"go/constant"
+	gotoken "go/token"
+	"llvm-lang/token"
+	"reflect"
+)
+
+var (
+	tokenType    = reflect.TypeOf(token.Token{})
+	constantType = reflect.TypeOf((*constant.Value)(nil)).Elem()
+)
+
+// Equal reports whether two trees have the same shape and values, ignoring source positions and
+// spelling, so 1e2 and 100.0 are equal but 1 and 2 are not
+func Equal(a, b Node) bool {
+	return len(Diff(a, b)) == 0
+}
+
+// Diff lists every place a and b differ, ignoring source positions and spelling. Each entry starts with the
+// path to the differing field from the root, e.g. "Stmts[0].Expr.Right: 2 != 3".
+func Diff(a, b Node) []string {
+	d := &differ{diffs: make([]string, 0)}
+	d.diff("", reflect.ValueOf(a), reflect.ValueOf(b))
+	return d.diffs
+}
+
+type differ struct {
+	diffs []string
+}
+
+func (d *differ) report(path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "<root>"
+	}
+	d.diffs = append(d.diffs, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (d *differ) diff(path string, a, b reflect.Value) {
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.report(path, "%s != %s", describe(a), describe(b))
+		}
+		return
+	}
+
+	if a.Type() != b.Type() {
+		d.report(path, "%s != %s", describe(a), describe(b))
+		return
+	}
+
+	// constants are compared by value, not by their internal representation
+	if a.Type() == constantType && !a.IsNil() && !b.IsNil() {
+		if !constant.Compare(a.Interface().(constant.Value), gotoken.EQL, b.Interface().(constant.Value)) {
+			d.report(path, "%s != %s", a.Interface(), b.Interface())
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.report(path, "%s != %s", describe(a), describe(b))
+			}
+			return
+		}
+		d.diff(path, a.Elem(), b.Elem())
+
+	case reflect.Struct:
+		// a token's position and spelling don't carry meaning, the node's own fields do
+		if a.Type() == tokenType {
+			if a.Interface().(token.Token).Type != b.Interface().(token.Token).Type {
+				d.report(path, "%s != %s", a.Interface().(token.Token).Type, b.Interface().(token.Token).Type)
+			}
+			return
+		}
+		for i := 0; i < a.NumField(); i++ {
+			name := a.Type().Field(i).Name
+			if path != "" {
+				name = path + "." + name
+			}
+			d.diff(name, a.Field(i), b.Field(i))
+		}
+
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			d.report(path, "length %d != %d", a.Len(), b.Len())
+			return
+		}
+		for i := 0; i < a.Len(); i++ {
+			d.diff(fmt.Sprintf("%s[%d]", path, i), a.Index(i), b.Index(i))
+		}
+
+	default:
+		if a.Interface() != b.Interface() {
+			d.report(path, "%v != %v", a.Interface(), b.Interface())
+		}
+	}
+}
+
+// prints a node by its source form where possible, otherwise by its Go type
+func describe(v reflect.Value) string {
+	if !v.IsValid() || ((v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && v.IsNil()) {
+		return "nil"
+	}
+	if node, ok := v.Interface().(Node); ok {
+		return fmt.Sprintf("%T(%s)", node, node.String())
+	}
+	return v.Type().String()
+}
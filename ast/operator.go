@@ -0,0 +1,109 @@
+package ast
+
+import "llvm-lang/token"
+
+// Operator identifies the operator of a prefix, infix or assignment expression, so consumers can
+// switch on it instead of matching strings. The node's token keeps the original spelling.
+type Operator int
+
+const (
+	OpIllegal Operator = iota
+
+	// Arithmetic
+	OpPlus
+	OpMinus
+	OpMultiply
+	OpDivide
+	OpModulo
+	OpPower
+
+	// Comparison
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpGreater
+	OpLessEqual
+	OpGreaterEqual
+	OpIn
+
+	// Logical
+	OpAnd
+	OpOr
+	OpNot
+
+	// Updates
+	OpIncrement
+	OpDecrement
+	OpAssign
+	OpPlusAssign
+	OpMinusAssign
+	OpMultiplyAssign
+	OpDivideAssign
+	OpModuloAssign
+)
+
+var operators = map[token.TokenType]Operator{
+	token.Plus:               OpPlus,
+	token.Minus:              OpMinus,
+	token.Star:               OpMultiply,
+	token.Slash:              OpDivide,
+	token.Modulo:             OpModulo,
+	token.Power:              OpPower,
+	token.EqualTo:            OpEqual,
+	token.NotEqualTo:         OpNotEqual,
+	token.LessThan:           OpLess,
+	token.GreaterThan:        OpGreater,
+	token.LessThanEqualTo:    OpLessEqual,
+	token.GreaterThanEqualTo: OpGreaterEqual,
+	token.In:                 OpIn,
+	token.And:                OpAnd,
+	token.Or:                 OpOr,
+	token.Bang:               OpNot,
+	token.Increment:          OpIncrement,
+	token.Decrement:          OpDecrement,
+	token.Assign:             OpAssign,
+	token.PlusAssign:         OpPlusAssign,
+	token.MinusAssign:        OpMinusAssign,
+	token.StarAssign:         OpMultiplyAssign,
+	token.SlashAssign:        OpDivideAssign,
+	token.ModuloAssign:       OpModuloAssign,
+}
+
+var operatorSpellings = map[Operator]string{
+	OpPlus:           "+",
+	OpMinus:          "-",
+	OpMultiply:       "*",
+	OpDivide:         "/",
+	OpModulo:         "%",
+	OpPower:          "**",
+	OpEqual:          "==",
+	OpNotEqual:       "!=",
+	OpLess:           "<",
+	OpGreater:        ">",
+	OpLessEqual:      "<=",
+	OpGreaterEqual:   ">=",
+	OpIn:             "in",
+	OpAnd:            "&&",
+	OpOr:             "||",
+	OpNot:            "!",
+	OpIncrement:      "++",
+	OpDecrement:      "--",
+	OpAssign:         "=",
+	OpPlusAssign:     "+=",
+	OpMinusAssign:    "-=",
+	OpMultiplyAssign: "*=",
+	OpDivideAssign:   "/=",
+	OpModuloAssign:   "%=",
+}
+
+// OperatorFor maps an operator token type to its Operator, or OpIllegal if it isn't one
+func OperatorFor(t token.TokenType) Operator {
+	return operators[t]
+}
+
+func (o Operator) String() string {
+	if spelling, ok := operatorSpellings[o]; ok {
+		return spelling
+	}
+	return "illegal"
+}
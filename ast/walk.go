@@ -0,0 +1,148 @@
+package ast
+
+// Children returns the direct child nodes of node in source order, skipping missing (nil) ones
+func Children(node Node) []Node {
+	children := make([]Node, 0)
+	add := func(nodes ...Node) {
+		for _, n := range nodes {
+			if n != nil {
+				children = append(children, n)
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, stmt := range n.Stmts {
+			add(stmt)
+		}
+	case *ExpressionStmt:
+		add(n.Expr)
+	case *BlockStmt:
+		for _, stmt := range n.Stmts {
+			add(stmt)
+		}
+	case *StructDecl:
+		addAttributes(add, n.Attributes)
+		add(n.Name)
+		for _, field := range n.Fields {
+			add(field.Name, field.Type)
+		}
+	case *EnumDecl:
+		add(n.Name)
+		for _, variant := range n.Variants {
+			add(variant.Name)
+			for _, t := range variant.Payload {
+				add(t)
+			}
+		}
+	case *LetStmt:
+		add(n.Name)
+		if n.Type != nil {
+			add(n.Type)
+		}
+		add(n.Value)
+	case *ConstDecl:
+		add(n.Name, n.Value)
+	case *StaticAssert:
+		add(n.Condition, n.Message)
+	case *ForStmt:
+		add(n.Var, n.Iterable, n.Body)
+	case *SwitchStmt:
+		add(n.Subject)
+		for _, c := range n.Cases {
+			for _, value := range c.Values {
+				add(value)
+			}
+			add(c.Body)
+		}
+	case *TestDecl:
+		add(n.Name, n.Body)
+	case *InterpolatedString:
+		for _, part := range n.Parts {
+			add(part)
+		}
+	case *PrefixExpr:
+		add(n.Right)
+	case *PostfixExpr:
+		add(n.Left)
+	case *InfixExpr:
+		add(n.Left, n.Right)
+	case *AssignExpr:
+		add(n.Target, n.Value)
+	case *SequenceExpr:
+		for _, expr := range n.Exprs {
+			add(expr)
+		}
+	case *ConditionalExpr:
+		add(n.Condition, n.Consequence, n.Alternative)
+	case *RangeExpr:
+		add(n.Start, n.End)
+	case *CastExpr:
+		add(n.Value, n.Type)
+	case *MatchExpr:
+		add(n.Subject)
+		for _, arm := range n.Arms {
+			add(arm.Pattern, arm.Body)
+		}
+	case *TryExpr:
+		add(n.Body)
+		if n.Err != nil {
+			add(n.Err)
+		}
+		add(n.Handler)
+	case *CallExpr:
+		add(n.Function)
+		for _, arg := range n.Arguments {
+			add(arg)
+		}
+	case *StructLiteral:
+		add(n.Type)
+		for _, field := range n.Fields {
+			add(field.Name, field.Value)
+		}
+	case *FieldAccessExpr:
+		add(n.Object, n.Field)
+	case *FunctionLiteral:
+		addAttributes(add, n.Attributes)
+		if n.Name != nil {
+			add(n.Name)
+		}
+		for _, t := range n.TypeParams {
+			add(t)
+		}
+		for _, param := range n.Parameters {
+			add(param.Name)
+			if param.Type != nil {
+				add(param.Type)
+			}
+		}
+		if n.ReturnType != nil {
+			add(n.ReturnType)
+		}
+		add(n.Body)
+	}
+
+	return children
+}
+
+// attributes aren't nodes themselves, their names and arguments are
+func addAttributes(add func(...Node), attrs []*Attribute) {
+	for _, attr := range attrs {
+		add(attr.Name)
+		for _, arg := range attr.Args {
+			add(arg)
+		}
+	}
+}
+
+// Inspect walks the tree depth first, calling fn on each node before its children.
+// Returning false from fn skips that node's children.
+func Inspect(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	for _, child := range Children(node) {
+		Inspect(child, fn)
+	}
+}
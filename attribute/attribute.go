@@ -0,0 +1,146 @@
+// Package attribute describes the attributes declarations can carry, like @inline or @export("name"). sema
+// checks every attribute in a program against its Spec, so backends can look up the ones they act on with
+// Find and trust what they get.
+package attribute
+
+import (
+	"errors"
+	"fmt"
+	"llvm-lang/ast"
+	"llvm-lang/irname"
+	"sync"
+)
+
+// Target is the set of declarations an attribute may go on
+type Target int
+
+const (
+	Function Target = 1 << iota
+	Struct
+)
+
+func (t Target) String() string {
+	switch t {
+	case Function:
+		return "functions"
+	case Struct:
+		return "structs"
+	default:
+		return "functions and structs"
+	}
+}
+
+type Spec struct {
+	Name    string
+	Doc     string
+	Targets Target
+
+	// how many arguments it takes, written without parentheses when MaxArgs is zero
+	MinArgs, MaxArgs int
+
+	// Check validates the arguments once their count is right, nil accepts any
+	Check func(attr *ast.Attribute) error
+
+	// Conflicts names attributes that can't go on the same declaration as this one
+	Conflicts []string
+}
+
+var (
+	specsMu sync.RWMutex
+	specs   = make(map[string]*Spec)
+)
+
+func init() {
+	Register(&Spec{Name: "inline", Doc: "always inline calls to the function", Targets: Function, Conflicts: []string{"noinline"}})
+	Register(&Spec{Name: "noinline", Doc: "never inline calls to the function", Targets: Function, Conflicts: []string{"inline"}})
+	Register(&Spec{Name: "export", Doc: "give the function external linkage under the C name given", Targets: Function, MinArgs: 1, MaxArgs: 1, Check: checkExport})
+	Register(&Spec{Name: "deprecated", Doc: "warn wherever the declaration is used, with the message given if any", Targets: Function | Struct, MaxArgs: 1, Check: checkDeprecated})
+}
+
+// Register adds spec, replacing any attribute of the same name. Backends register the attributes only they
+// understand this way.
+func Register(spec *Spec) {
+	specsMu.Lock()
+	defer specsMu.Unlock()
+	specs[spec.Name] = spec
+}
+
+func Lookup(name string) (*Spec, bool) {
+	specsMu.RLock()
+	defer specsMu.RUnlock()
+	spec, ok := specs[name]
+	return spec, ok
+}
+
+// Find returns the attribute called name among attrs
+func Find(attrs []*ast.Attribute, name string) (*ast.Attribute, bool) {
+	for _, attr := range attrs {
+		if attr.Name.Value == name {
+			return attr, true
+		}
+	}
+	return nil, false
+}
+
+// Validate checks attr against its spec for a declaration of kind target, returning what is wrong with it
+func Validate(attr *ast.Attribute, target Target) error {
+	spec, ok := Lookup(attr.Name.Value)
+	switch {
+	case !ok:
+		return errors.New("there is no such attribute")
+	case spec.Targets&target == 0:
+		return fmt.Errorf("it only goes on %s", spec.Targets)
+	case len(attr.Args) < spec.MinArgs || len(attr.Args) > spec.MaxArgs:
+		return fmt.Errorf("it takes %s, got %d", arguments(spec), len(attr.Args))
+	case spec.Check != nil:
+		return spec.Check(attr)
+	}
+	return nil
+}
+
+func arguments(spec *Spec) string {
+	switch {
+	case spec.MaxArgs == 0:
+		return "no arguments"
+	case spec.MinArgs == spec.MaxArgs:
+		return count(spec.MaxArgs)
+	case spec.MinArgs == 0:
+		return "at most " + count(spec.MaxArgs)
+	}
+	return fmt.Sprintf("%d to %d arguments", spec.MinArgs, spec.MaxArgs)
+}
+
+func count(n int) string {
+	if n == 1 {
+		return "one argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
+
+// StringArg returns argument i of attr, which must be a string literal. Attributes that haven't been through
+// Validate may have fewer arguments than asked for, that is an error too.
+func StringArg(attr *ast.Attribute, i int) (string, error) {
+	if i >= len(attr.Args) {
+		return "", fmt.Errorf("argument %d is missing, @%s has %s", i+1, attr.Name.Value, count(len(attr.Args)))
+	}
+	if s, ok := attr.Args[i].(*ast.StringLiteral); ok {
+		return s.Value, nil
+	}
+	return "", fmt.Errorf("argument %d must be a string literal, got %s", i+1, attr.Args[i].String())
+}
+
+func checkExport(attr *ast.Attribute) error {
+	name, err := StringArg(attr, 0)
+	if err != nil {
+		return err
+	}
+	return irname.ValidateExport(name)
+}
+
+func checkDeprecated(attr *ast.Attribute) error {
+	if len(attr.Args) == 0 {
+		return nil
+	}
+	_, err := StringArg(attr, 0)
+	return err
+}
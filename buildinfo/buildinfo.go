@@ -0,0 +1,126 @@
+// Package buildinfo describes the compiler build: its version, the commit it was built from and the language
+// features it understands. Tools use it to tell compilers apart, the language server in its handshake and
+// caches in their keys. Release builds stamp Version and Commit at link time:
+//
+//	go build -ldflags "-X llvm-lang/buildinfo.Version=v0.4.0 -X llvm-lang/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// Builds that don't fall back on what the Go toolchain recorded about the module and its checkout.
+package buildinfo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Set with -ldflags -X, empty means look in the Go build info instead
+var (
+	Version string
+	Commit  string
+)
+
+// features are named for the syntax they add, and a name is never reused for something else once released.
+// A change adding syntax adds its name here.
+var features = []string{
+	"assignment",
+	"attributes",
+	"booleans",
+	"byte-literals",
+	"casts",
+	"compound-assignment",
+	"const",
+	"doc-comments",
+	"enums",
+	"extended-number-literals",
+	"for-in",
+	"function-literals",
+	"generics",
+	"heredocs",
+	"in-operator",
+	"increment-decrement",
+	"interpolation",
+	"lambdas",
+	"match",
+	"nil",
+	"postfix-increment-decrement",
+	"power-operator",
+	"pure-functions",
+	"ranges",
+	"raw-strings",
+	"sequence-expressions",
+	"static-assert",
+	"structs",
+	"switch",
+	"ternary",
+	"tests",
+	"try-catch",
+	"type-annotations",
+	"variadics",
+}
+
+type Info struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit"` // empty when it isn't known
+	GoVersion string   `json:"goVersion"`
+	Features  []string `json:"features"` // sorted
+}
+
+// Get returns the info for the running compiler
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, GoVersion: runtime.Version(), Features: Features()}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "devel"
+	}
+	return info
+}
+
+// Features returns the names of the supported language features, sorted
+func Features() []string {
+	out := append([]string(nil), features...)
+	sort.Strings(out)
+	return out
+}
+
+// Supports reports whether feature is one of the names Features returns
+func Supports(feature string) bool {
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheKey changes whenever the compiler could produce different output, so results cached under one build
+// are never picked up by another
+func (i Info) CacheKey() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{i.Version, i.Commit, i.GoVersion, strings.Join(i.Features, ",")}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// String is the one line llvm-lang version prints, like "llvm-lang v0.4.0 (3f2a1c9) go1.20"
+func (i Info) String() string {
+	out := "llvm-lang " + i.Version
+	if i.Commit != "" {
+		commit := i.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		out += " (" + commit + ")"
+	}
+	return out + " " + i.GoVersion
+}
@@ -0,0 +1,50 @@
+package buildinfo
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	got := Features()
+	if !sort.StringsAreSorted(got) {
+		t.Errorf("Features isn't sorted: %q", got)
+	}
+	seen := make(map[string]bool)
+	for _, feature := range got {
+		if seen[feature] {
+			t.Errorf("%s is listed twice", feature)
+		}
+		seen[feature] = true
+		if !Supports(feature) {
+			t.Errorf("Supports(%q) is false", feature)
+		}
+	}
+	if Supports("modules") {
+		t.Error("Supports(\"modules\") is true")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	info := Info{Version: "v0.4.0", Commit: "3f2a1c9", GoVersion: "go1.20", Features: Features()}
+	if info.CacheKey() != info.CacheKey() {
+		t.Error("the cache key of the same build changed")
+	}
+
+	other := info
+	other.Features = append(append([]string(nil), info.Features...), "modules")
+	if other.CacheKey() == info.CacheKey() {
+		t.Error("adding a feature left the cache key as it was")
+	}
+}
+
+func TestString(t *testing.T) {
+	info := Info{Version: "v0.4.0", Commit: "3f2a1c9d8e7f", GoVersion: "go1.20"}
+	if got, want := info.String(), "llvm-lang v0.4.0 (3f2a1c9) go1.20"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	info.Commit = ""
+	if got, want := info.String(), "llvm-lang v0.4.0 go1.20"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,137 @@
+// Package callgraph works out which named functions call which, from the calls whose callee resolves to a
+// function definition. Calls through parameters, variables or fields can't be known without running the
+// program and are left out.
+package callgraph
+
+import (
+	"llvm-lang/ast"
+	"llvm-lang/index"
+	"llvm-lang/token"
+	"sort"
+)
+
+type Function struct {
+	ID   int // position in Graph.Functions
+	Name string
+	Pos  token.Position
+}
+
+// A Call is one call site, Caller is nil for calls made by top-level expressions
+type Call struct {
+	Caller *Function
+	Callee *Function
+	Pos    token.Position
+}
+
+type Graph struct {
+	Functions []*Function // in definition order
+	Calls     []*Call     // in source order
+}
+
+func Build(program *ast.Program) *Graph {
+	ix := index.Build(program)
+	g := &Graph{Functions: make([]*Function, 0), Calls: make([]*Call, 0)}
+
+	functions := make(map[*index.Symbol]*Function)
+	for _, sym := range ix.Symbols {
+		if sym.Kind == index.Function {
+			fn := &Function{ID: len(g.Functions), Name: sym.Name, Pos: sym.Def.Token.Pos}
+			g.Functions = append(g.Functions, fn)
+			functions[sym] = fn
+		}
+	}
+
+	var visit func(node ast.Node, caller *Function)
+	visit = func(node ast.Node, caller *Function) {
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			if sym, ok := ix.Lookup(n.Name); n.Name != nil && ok {
+				caller = functions[sym] // lambdas are part of whatever function they appear in
+			}
+		case *ast.CallExpr:
+			if ident, ok := n.Function.(*ast.Identifier); ok {
+				if sym, ok := ix.Lookup(ident); ok && functions[sym] != nil {
+					g.Calls = append(g.Calls, &Call{Caller: caller, Callee: functions[sym], Pos: n.Token.Pos})
+				}
+			}
+		}
+		for _, child := range ast.Children(node) {
+			visit(child, caller)
+		}
+	}
+	visit(program, nil)
+
+	return g
+}
+
+// Recursive returns the groups of functions that can end up calling themselves, directly or through each
+// other, each group in definition order
+func (g *Graph) Recursive() [][]*Function {
+	callees := make(map[*Function][]*Function)
+	selfCalls := make(map[*Function]bool)
+	for _, call := range g.Calls {
+		if call.Caller == nil {
+			continue
+		}
+		callees[call.Caller] = append(callees[call.Caller], call.Callee)
+		if call.Caller == call.Callee {
+			selfCalls[call.Caller] = true
+		}
+	}
+
+	// Tarjan's strongly connected components
+	order := make(map[*Function]int)
+	lowlink := make(map[*Function]int)
+	onStack := make(map[*Function]bool)
+	stack := make([]*Function, 0)
+	groups := make([][]*Function, 0)
+
+	var connect func(fn *Function)
+	connect = func(fn *Function) {
+		order[fn] = len(order)
+		lowlink[fn] = order[fn]
+		stack = append(stack, fn)
+		onStack[fn] = true
+
+		for _, callee := range callees[fn] {
+			if _, seen := order[callee]; !seen {
+				connect(callee)
+				lowlink[fn] = min(lowlink[fn], lowlink[callee])
+			} else if onStack[callee] {
+				lowlink[fn] = min(lowlink[fn], order[callee])
+			}
+		}
+
+		if lowlink[fn] != order[fn] {
+			return
+		}
+		group := make([]*Function, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			group = append(group, top)
+			if top == fn {
+				break
+			}
+		}
+		if len(group) > 1 || selfCalls[fn] {
+			sort.Slice(group, func(i, j int) bool { return group[i].ID < group[j].ID })
+			groups = append(groups, group)
+		}
+	}
+
+	for _, fn := range g.Functions {
+		if _, seen := order[fn]; !seen {
+			connect(fn)
+		}
+	}
+	return groups
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,87 @@
+package callgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Dot renders g as a Graphviz digraph with one edge per caller and callee pair, however many call sites
+// there are. Calls from top-level expressions come from a node labelled <top level>.
+func (g *Graph) Dot() string {
+	var out bytes.Buffer
+
+	out.WriteString("digraph calls {\n")
+	out.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	for _, fn := range g.Functions {
+		fmt.Fprintf(&out, "  f%d [label=%s];\n", fn.ID, strconv.Quote(fn.Name))
+	}
+
+	seen := make(map[[2]string]bool)
+	topLevel := false
+	for _, call := range g.Calls {
+		caller := "top"
+		if call.Caller != nil {
+			caller = fmt.Sprintf("f%d", call.Caller.ID)
+		} else if !topLevel {
+			topLevel = true
+			out.WriteString("  top [label=\"<top level>\", shape=ellipse];\n")
+		}
+
+		edge := [2]string{caller, fmt.Sprintf("f%d", call.Callee.ID)}
+		if seen[edge] {
+			continue
+		}
+		seen[edge] = true
+		fmt.Fprintf(&out, "  %s -> %s;\n", edge[0], edge[1])
+	}
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+type jsonFunction struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+type jsonCall struct {
+	Caller *int `json:"caller"` // null for top-level calls
+	Callee int  `json:"callee"`
+	Line   int  `json:"line"`
+	Column int  `json:"column"`
+}
+
+type jsonGraph struct {
+	Functions []jsonFunction `json:"functions"`
+	Calls     []jsonCall     `json:"calls"`
+	Recursive [][]int        `json:"recursive"`
+}
+
+// JSON encodes g with functions referred to by id, along with the ids of each recursive group
+func (g *Graph) JSON() ([]byte, error) {
+	out := jsonGraph{Functions: make([]jsonFunction, 0, len(g.Functions)), Calls: make([]jsonCall, 0, len(g.Calls)), Recursive: make([][]int, 0)}
+
+	for _, fn := range g.Functions {
+		out.Functions = append(out.Functions, jsonFunction{ID: fn.ID, Name: fn.Name, Line: fn.Pos.Line, Column: fn.Pos.Column})
+	}
+	for _, call := range g.Calls {
+		c := jsonCall{Callee: call.Callee.ID, Line: call.Pos.Line, Column: call.Pos.Column}
+		if call.Caller != nil {
+			c.Caller = &call.Caller.ID
+		}
+		out.Calls = append(out.Calls, c)
+	}
+	for _, group := range g.Recursive() {
+		ids := make([]int, 0, len(group))
+		for _, fn := range group {
+			ids = append(ids, fn.ID)
+		}
+		out.Recursive = append(out.Recursive, ids)
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
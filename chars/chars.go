@@ -0,0 +1,74 @@
+// Package chars classifies characters for everything that needs to agree with the lexer about
+// what an identifier or number looks like: the lexer itself, and later the formatter and completion.
+package chars
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	identStart = 1 << iota
+	digit
+	hexDigit
+	space
+)
+
+// class flags for every ASCII character, anything outside ASCII falls back to the unicode tables
+var table = [utf8.RuneSelf]uint8{}
+
+func init() {
+	for c := 'a'; c <= 'z'; c++ {
+		table[c] |= identStart
+	}
+	for c := 'A'; c <= 'Z'; c++ {
+		table[c] |= identStart
+	}
+	table['_'] |= identStart
+
+	for c := '0'; c <= '9'; c++ {
+		table[c] |= digit | hexDigit
+	}
+	for c := 'a'; c <= 'f'; c++ {
+		table[c] |= hexDigit
+		table[c-'a'+'A'] |= hexDigit
+	}
+
+	for _, c := range " \t\n\r" {
+		table[c] |= space
+	}
+}
+
+func is(r rune, class uint8) bool {
+	return r >= 0 && r < utf8.RuneSelf && table[r]&class != 0
+}
+
+// IsIdentStart reports whether r can begin an identifier: a letter or underscore
+func IsIdentStart(r rune) bool {
+	if r < utf8.RuneSelf {
+		return is(r, identStart)
+	}
+	return unicode.IsLetter(r)
+}
+
+// IsIdentContinue reports whether r can appear after the first character of an identifier
+func IsIdentContinue(r rune) bool {
+	if r < utf8.RuneSelf {
+		return is(r, identStart|digit)
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// IsDigit reports whether r is an ASCII decimal digit, the only digits number literals accept
+func IsDigit(r rune) bool {
+	return is(r, digit)
+}
+
+func IsHexDigit(r rune) bool {
+	return is(r, hexDigit)
+}
+
+// IsSpace reports whether r is whitespace the lexer skips between tokens
+func IsSpace(r rune) bool {
+	return is(r, space)
+}
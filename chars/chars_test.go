@@ -0,0 +1,87 @@
+package chars
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// what each class means spelled out plainly, the tables must agree with these for every rune
+var definitions = []struct {
+	name  string
+	class func(rune) bool
+	want  func(rune) bool
+}{
+	{"IsIdentStart", IsIdentStart, func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || r >= 0x80 && unicode.IsLetter(r)
+	}},
+	{"IsIdentContinue", IsIdentContinue, func(r rune) bool {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || r >= '0' && r <= '9' ||
+			r >= 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+	}},
+	{"IsDigit", IsDigit, func(r rune) bool { return r >= '0' && r <= '9' }},
+	{"IsHexDigit", IsHexDigit, func(r rune) bool {
+		return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+	}},
+	{"IsSpace", IsSpace, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' || r == '\r' }},
+}
+
+func TestClassesMatchDefinitions(t *testing.T) {
+	for _, d := range definitions {
+		for r := rune(-1); r <= unicode.MaxRune+1; r++ {
+			if got, want := d.class(r), d.want(r); got != want {
+				t.Errorf("%s(%U) = %v, want %v", d.name, r, got, want)
+			}
+		}
+	}
+}
+
+func TestExamples(t *testing.T) {
+	tests := []struct {
+		r                                     rune
+		start, cont, digit, hexDigit, isSpace bool
+	}{
+		{'x', true, true, false, false, false},
+		{'_', true, true, false, false, false},
+		{'7', false, true, true, true, false},
+		{'F', true, true, false, true, false},
+		{'g', true, true, false, false, false},
+		{'\t', false, false, false, false, true},
+		{'$', false, false, false, false, false},
+		{'é', true, true, false, false, false},
+		{'λ', true, true, false, false, false},
+		{'٣', false, true, false, false, false}, // ARABIC-INDIC DIGIT THREE, a digit but not one numbers accept
+		{' ', false, false, false, false, false},
+		{'🙂', false, false, false, false, false},
+	}
+	for _, test := range tests {
+		got := [5]bool{IsIdentStart(test.r), IsIdentContinue(test.r), IsDigit(test.r), IsHexDigit(test.r), IsSpace(test.r)}
+		want := [5]bool{test.start, test.cont, test.digit, test.hexDigit, test.isSpace}
+		if got != want {
+			t.Errorf("%q: start, continue, digit, hex digit, space = %v, want %v", test.r, got, want)
+		}
+	}
+}
+
+// a mix of identifiers, numbers, operators and a little non-ASCII, like the lexer sees
+var benchSource = []rune(strings.Repeat("def area(shape: Shape, scale_2: float): float { let r = 0x1F * 3.25e2; naïve + r } ", 64))
+
+func benchmarkClass(b *testing.B, class func(rune) bool) {
+	n := 0
+	for i := 0; i < b.N; i++ {
+		for _, r := range benchSource {
+			if class(r) {
+				n++
+			}
+		}
+	}
+	sink = n
+}
+
+var sink int
+
+func BenchmarkIsIdentStart(b *testing.B)    { benchmarkClass(b, IsIdentStart) }
+func BenchmarkIsIdentContinue(b *testing.B) { benchmarkClass(b, IsIdentContinue) }
+func BenchmarkIsDigit(b *testing.B)         { benchmarkClass(b, IsDigit) }
+func BenchmarkIsHexDigit(b *testing.B)      { benchmarkClass(b, IsHexDigit) }
+func BenchmarkIsSpace(b *testing.B)         { benchmarkClass(b, IsSpace) }
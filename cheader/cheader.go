@@ -0,0 +1,175 @@
+// Package cheader writes the C header for the functions a program exports with @export("c_name"), so C code
+// can call into a library built from it. Exported functions must annotate every parameter and their return
+// type, since C has no way to leave them out.
+package cheader
+
+import (
+	"bytes"
+	"fmt"
+	"llvm-lang/ast"
+	"llvm-lang/attribute"
+	"llvm-lang/irname"
+	"strings"
+)
+
+// the C spelling of each builtin type
+var builtins = map[string]string{
+	"int":    "int64_t",
+	"float":  "double",
+	"bool":   "bool",
+	"string": "const char *",
+}
+
+// Generate returns the header for program's top-level exported functions, wrapped in an include guard named
+// guard. The structs they take or return are declared first, along with the structs those contain.
+func Generate(program *ast.Program, guard string) (string, error) {
+	g := &generator{structs: make(map[string]*ast.StructDecl), declared: make(map[string]bool)}
+	exported := make([]*ast.FunctionLiteral, 0)
+	for _, stmt := range program.Stmts {
+		switch s := stmt.(type) {
+		case *ast.StructDecl:
+			g.structs[s.Name.Value] = s
+		case *ast.ExpressionStmt:
+			if fn, ok := s.Expr.(*ast.FunctionLiteral); ok && fn.Name != nil {
+				if _, ok := attribute.Find(fn.Attributes, "export"); ok {
+					exported = append(exported, fn)
+				}
+			}
+		}
+	}
+
+	prototypes := make([]string, 0, len(exported))
+	exporters := make(map[string]string) // C name -> the function exported under it
+	for _, fn := range exported {
+		prototype, name, err := g.prototype(fn)
+		if err != nil {
+			return "", err
+		}
+		if other, ok := exporters[name]; ok {
+			return "", fmt.Errorf("%s: %s is already exported as %s", fn.Name.Value, other, name)
+		}
+		exporters[name] = fn.Name.Value
+		prototypes = append(prototypes, prototype)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "#ifndef %s\n#define %s\n\n#include <stdbool.h>\n#include <stdint.h>\n\n", guard, guard)
+	out.WriteString("#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	for _, decl := range g.decls {
+		out.WriteString(decl + "\n\n")
+	}
+	for _, prototype := range prototypes {
+		out.WriteString(prototype + "\n")
+	}
+	out.WriteString("\n#ifdef __cplusplus\n}\n#endif\n\n")
+	fmt.Fprintf(&out, "#endif /* %s */\n", guard)
+	return out.String(), nil
+}
+
+type generator struct {
+	structs  map[string]*ast.StructDecl
+	declared map[string]bool // structs already in decls, or on their way there
+	decls    []string        // struct definitions, each after the ones it contains
+}
+
+// the prototype for fn and the C name it is exported under
+func (g *generator) prototype(fn *ast.FunctionLiteral) (string, string, error) {
+	prototype, name, err := g.exportedPrototype(fn)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", fn.Name.Value, err)
+	}
+	return prototype, name, nil
+}
+
+func (g *generator) exportedPrototype(fn *ast.FunctionLiteral) (string, string, error) {
+	export, _ := attribute.Find(fn.Attributes, "export")
+	name, err := attribute.StringArg(export, 0)
+	if err != nil {
+		return "", "", err
+	}
+	if err := irname.ValidateExport(name); err != nil {
+		return "", "", err
+	}
+	if fn.ReturnType == nil {
+		return "", "", fmt.Errorf("exported functions need a return type")
+	}
+	if len(fn.TypeParams) > 0 || fn.Variadic {
+		return "", "", fmt.Errorf("generic and variadic functions can't be exported to C")
+	}
+
+	params := make([]string, 0, len(fn.Parameters))
+	for _, param := range fn.Parameters {
+		if param.Type == nil {
+			return "", "", fmt.Errorf("parameter %s needs a type to be exported", param.Name.Value)
+		}
+		t, err := g.cType(param.Type)
+		if err != nil {
+			return "", "", err
+		}
+		params = append(params, declarator(t, paramName(param.Name.Value)))
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+
+	result, err := g.cType(fn.ReturnType)
+	if err != nil {
+		return "", "", err
+	}
+	return declarator(result, name) + "(" + strings.Join(params, ", ") + ");", name, nil
+}
+
+// parameter names in a prototype are only documentation, so one C reserves gets an underscore rather than an error
+func paramName(name string) string {
+	if irname.IsCKeyword(name) {
+		return name + "_"
+	}
+	return name
+}
+
+// a type followed by a name, without a space after a pointer's *
+func declarator(t string, name string) string {
+	if strings.HasSuffix(t, "*") {
+		return t + name
+	}
+	return t + " " + name
+}
+
+func (g *generator) cType(t *ast.Identifier) (string, error) {
+	if c, ok := builtins[t.Value]; ok {
+		return c, nil
+	}
+	decl, ok := g.structs[t.Value]
+	if !ok {
+		return "", fmt.Errorf("type %s has no C equivalent", t.Value)
+	}
+	if irname.IsCKeyword(t.Value) {
+		return "", fmt.Errorf("struct %s can't be declared in C, its name is a keyword there", t.Value)
+	}
+	if err := g.declare(decl); err != nil {
+		return "", err
+	}
+	return "struct " + t.Value, nil
+}
+
+func (g *generator) declare(decl *ast.StructDecl) error {
+	if g.declared[decl.Name.Value] {
+		return nil // done already, or a struct containing itself, which the checker will have to reject
+	}
+	g.declared[decl.Name.Value] = true
+
+	fields := make([]string, 0, len(decl.Fields))
+	for _, field := range decl.Fields {
+		// C code reaches fields by name, so unlike a parameter a field can't just be renamed
+		if irname.IsCKeyword(field.Name.Value) {
+			return fmt.Errorf("struct %s: field %s can't be declared in C, its name is a keyword there", decl.Name.Value, field.Name.Value)
+		}
+		t, err := g.cType(field.Type)
+		if err != nil {
+			return fmt.Errorf("struct %s: %w", decl.Name.Value, err)
+		}
+		fields = append(fields, "    "+declarator(t, field.Name.Value)+";")
+	}
+	g.decls = append(g.decls, "struct "+decl.Name.Value+" {\n"+strings.Join(fields, "\n")+"\n};")
+	return nil
+}
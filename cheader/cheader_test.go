@@ -0,0 +1,64 @@
+package cheader
+
+import (
+	"llvm-lang/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func generate(t *testing.T, src string) (string, error) {
+	t.Helper()
+	program, diags, _ := parser.Parse(nil, []byte(src))
+	if len(diags) > 0 {
+		t.Fatalf("parse %q: %v", src, diags)
+	}
+	return Generate(program, "TEST_H")
+}
+
+func TestKeywordParameterIsRenamed(t *testing.T) {
+	header, err := generate(t, `@export("scale") def scale(double: float, x: int): float { double }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "double scale(double double_, int64_t x);"; !strings.Contains(header, want) {
+		t.Errorf("header lacks %q:\n%s", want, header)
+	}
+
+	// the header has to compile as C and as C++, when there's a compiler to try
+	if _, err := exec.LookPath("gcc"); err != nil {
+		return
+	}
+	path := filepath.Join(t.TempDir(), "test.h")
+	if err := os.WriteFile(path, []byte(header), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, lang := range []string{"c", "c++"} {
+		if out, err := exec.Command("gcc", "-fsyntax-only", "-x", lang, path).CombinedOutput(); err != nil {
+			t.Errorf("gcc -x %s rejects the header: %v\n%s", lang, err, out)
+		}
+	}
+}
+
+func TestRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"keyword export name", `@export("int") def f(): int { 1 }`, "keyword"},
+		{"duplicate export name", `@export("a") def f(): int { 1 } @export("a") def g(): int { 2 }`, "already exported as a"},
+		{"bare export", `@export def f(): int { 1 }`, "argument 1 is missing"},
+		{"keyword field", `struct P { long: int } @export("f") def f(p: P): int { 1 }`, "field long"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := generate(t, test.src)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %v, want one mentioning %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import "os"
+
+// how text diagnostics are colored, set by --color: auto, always or never
+var colorMode = "auto"
+
+// reports whether diagnostics written to f get colors. In auto mode that takes f being a terminal, and neither
+// NO_COLOR set nor TERM=dumb.
+func useColor(f *os.File) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// character devices are terminals, pipes and files are not
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
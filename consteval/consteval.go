@@ -0,0 +1,356 @@
+// Package consteval folds constant expressions, the initializers of const declarations and the conditions of
+// static asserts, to exact values at compile time. Numbers are folded with go/constant, so 0.1 + 0.2 == 0.3 holds exactly here even though it
+// doesn't in float64 at run time. Dividing two integers truncates toward zero as it does at run time, so 7 / 2
+// is 3 and -7 / 2 is -3.
+package consteval
+
+import (
+	"fmt"
+	"go/constant"
+	gotoken "go/token"
+	"llvm-lang/ast"
+	"llvm-lang/diagnostic"
+	"llvm-lang/index"
+	"llvm-lang/numfmt"
+	"math"
+)
+
+const (
+	codeNotConstant       = "E0300"
+	codeBadConstant       = "E0301"
+	codeAssertFailed      = "E0302"
+	codeAssertNotConstant = "E0303"
+	codeAssertBadConstant = "E0304"
+)
+
+// maxExactExponent bounds the integer powers folded exactly, past it ** falls back to float64
+const maxExactExponent = 1024
+
+// Check folds every const declaration in program and checks every static assert. Both may use constants
+// declared before them. Values has an entry for each constant that folded.
+func Check(program *ast.Program) (values map[string]constant.Value, diags []diagnostic.Diagnostic) {
+	ev := &evaluator{index: index.Build(program), values: make(map[*index.Symbol]constant.Value)}
+	values = make(map[string]constant.Value)
+	diags = make([]diagnostic.Diagnostic, 0)
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if assert, ok := node.(*ast.StaticAssert); ok {
+			if diag, failed := ev.assert(assert); failed {
+				diags = append(diags, diag)
+			}
+			return false
+		}
+
+		decl, ok := node.(*ast.ConstDecl)
+		if !ok || decl.Value == nil {
+			return true
+		}
+
+		value, err := ev.eval(decl.Value)
+		if err != nil {
+			diags = append(diags, err.diagnostic(decl))
+			return false
+		}
+		if sym, ok := ev.index.Lookup(decl.Name); ok {
+			ev.values[sym] = value
+		}
+		values[decl.Name.Value] = value
+		return false
+	})
+
+	return values, diags
+}
+
+// why an initializer didn't fold, and the part of it that didn't
+type evalError struct {
+	code    string
+	where   string
+	message string
+}
+
+func (e *evalError) diagnostic(decl *ast.ConstDecl) diagnostic.Diagnostic {
+	return diagnostic.New(decl.Token.Pos, e.code, decl.Name.Value, e.where, e.message)
+}
+
+func (e *evalError) assertDiagnostic(assert *ast.StaticAssert) diagnostic.Diagnostic {
+	code := codeAssertNotConstant
+	if e.code == codeBadConstant {
+		code = codeAssertBadConstant
+	}
+	return diagnostic.New(assert.Token.Pos, code, e.where, e.message)
+}
+
+type evaluator struct {
+	index  *index.Index
+	values map[*index.Symbol]constant.Value
+}
+
+func notConstant(node ast.Expr, format string, args ...interface{}) *evalError {
+	return &evalError{code: codeNotConstant, where: node.String(), message: fmt.Sprintf(format, args...)}
+}
+
+func badConstant(node ast.Expr, format string, args ...interface{}) *evalError {
+	return &evalError{code: codeBadConstant, where: node.String(), message: fmt.Sprintf(format, args...)}
+}
+
+// the message, when given, must be a constant string. Without one the condition is quoted instead.
+func (ev *evaluator) assert(assert *ast.StaticAssert) (diagnostic.Diagnostic, bool) {
+	ok, err := ev.boolean(assert.Condition)
+	if err != nil {
+		return err.assertDiagnostic(assert), true
+	}
+
+	message := assert.Condition.String()
+	if assert.Message != nil {
+		value, err := ev.eval(assert.Message)
+		if err == nil && value.Kind() != constant.String {
+			err = badConstant(assert.Message, "the message %s is not a string", describe(value))
+		}
+		if err != nil {
+			return err.assertDiagnostic(assert), true
+		}
+		message = constant.StringVal(value)
+	}
+
+	if ok {
+		return diagnostic.Diagnostic{}, false
+	}
+	return diagnostic.New(assert.Token.Pos, codeAssertFailed, message), true
+}
+
+func (ev *evaluator) eval(expr ast.Expr) (constant.Value, *evalError) {
+	if expr == nil {
+		return nil, &evalError{code: codeBadConstant, message: "part of it is missing"}
+	}
+
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		return e.Value, nil
+	case *ast.StringLiteral:
+		return constant.MakeString(e.Value), nil
+	case *ast.BooleanLiteral:
+		return constant.MakeBool(e.Value), nil
+	case *ast.Identifier:
+		sym, ok := ev.index.Lookup(e)
+		if !ok || sym.Kind != index.Const {
+			return nil, notConstant(e, "%s is not a constant", e.Value)
+		}
+		value, ok := ev.values[sym]
+		if !ok {
+			return nil, notConstant(e, "%s has no constant value", e.Value)
+		}
+		return value, nil
+	case *ast.PrefixExpr:
+		return ev.prefix(e)
+	case *ast.InfixExpr:
+		return ev.infix(e)
+	case *ast.CastExpr:
+		return ev.cast(e)
+	case *ast.SequenceExpr:
+		// constants have no effects, so only the last value matters, but every part has to be constant
+		var value constant.Value
+		for _, part := range e.Exprs {
+			var err *evalError
+			if value, err = ev.eval(part); err != nil {
+				return nil, err
+			}
+		}
+		return value, nil
+	case *ast.ConditionalExpr:
+		cond, err := ev.boolean(e.Condition)
+		if err != nil {
+			return nil, err
+		}
+		if cond {
+			return ev.eval(e.Consequence)
+		}
+		return ev.eval(e.Alternative)
+	}
+	return nil, notConstant(expr, "it can only be computed at run time")
+}
+
+func (ev *evaluator) boolean(expr ast.Expr) (bool, *evalError) {
+	value, err := ev.eval(expr)
+	if err != nil {
+		return false, err
+	}
+	if value.Kind() != constant.Bool {
+		return false, badConstant(expr, "%s is not a boolean", expr.String())
+	}
+	return constant.BoolVal(value), nil
+}
+
+func (ev *evaluator) prefix(e *ast.PrefixExpr) (constant.Value, *evalError) {
+	right, err := ev.eval(e.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case e.Operator == ast.OpMinus && isNumber(right):
+		return constant.UnaryOp(gotoken.SUB, right, 0), nil
+	case e.Operator == ast.OpNot && right.Kind() == constant.Bool:
+		return constant.UnaryOp(gotoken.NOT, right, 0), nil
+	}
+	return nil, badConstant(e, "%s cannot be applied to %s", e.Operator, describe(right))
+}
+
+// as int truncates toward zero, as float is exact for integers. Strings and booleans only convert to themselves.
+func (ev *evaluator) cast(e *ast.CastExpr) (constant.Value, *evalError) {
+	value, err := ev.eval(e.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case e.Type.Value == "int" && isNumber(value):
+		if value.Kind() == constant.Float {
+			value = truncate(value)
+		}
+		return constant.ToInt(value), nil
+	case e.Type.Value == "float" && isNumber(value):
+		return constant.ToFloat(value), nil
+	case e.Type.Value == "string" && value.Kind() == constant.String:
+		return value, nil
+	case e.Type.Value == "bool" && value.Kind() == constant.Bool:
+		return value, nil
+	}
+	return nil, badConstant(e, "%s cannot be converted to %s", describe(value), e.Type.Value)
+}
+
+// rounds a float toward zero, constant.ToInt only converts floats that are already whole
+func truncate(value constant.Value) constant.Value {
+	if f, exact := constant.Float64Val(value); !exact || math.Abs(f) < 1<<53 {
+		return constant.MakeFloat64(math.Trunc(f))
+	}
+	return value // past 2**53 every float64 is whole
+}
+
+var arithmetic = map[ast.Operator]gotoken.Token{
+	ast.OpPlus:     gotoken.ADD,
+	ast.OpMinus:    gotoken.SUB,
+	ast.OpMultiply: gotoken.MUL,
+	ast.OpDivide:   gotoken.QUO,
+}
+
+var comparisons = map[ast.Operator]gotoken.Token{
+	ast.OpEqual:        gotoken.EQL,
+	ast.OpNotEqual:     gotoken.NEQ,
+	ast.OpLess:         gotoken.LSS,
+	ast.OpGreater:      gotoken.GTR,
+	ast.OpLessEqual:    gotoken.LEQ,
+	ast.OpGreaterEqual: gotoken.GEQ,
+}
+
+func (ev *evaluator) infix(e *ast.InfixExpr) (constant.Value, *evalError) {
+	// && and || short circuit, so the right side only has to be constant if it is reached
+	if e.Operator == ast.OpAnd || e.Operator == ast.OpOr {
+		left, err := ev.boolean(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		if left == (e.Operator == ast.OpOr) {
+			return constant.MakeBool(left), nil
+		}
+		right, err := ev.boolean(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return constant.MakeBool(right), nil
+	}
+
+	left, err := ev.eval(e.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := ev.eval(e.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	if op, ok := comparisons[e.Operator]; ok && comparable(left, op, right) {
+		return constant.MakeBool(constant.Compare(left, op, right)), nil
+	}
+
+	if isNumber(left) && isNumber(right) {
+		switch e.Operator {
+		case ast.OpDivide, ast.OpModulo:
+			if constant.Sign(right) == 0 {
+				return nil, badConstant(e, "division by zero")
+			}
+		}
+
+		var result constant.Value
+		if e.Operator == ast.OpDivide && left.Kind() == constant.Int && right.Kind() == constant.Int {
+			result = constant.BinaryOp(left, gotoken.QUO_ASSIGN, right) // go/constant's integer division
+		} else if op, ok := arithmetic[e.Operator]; ok {
+			result = constant.BinaryOp(left, op, right)
+		} else if e.Operator == ast.OpModulo {
+			result = modulo(left, right)
+		} else if e.Operator == ast.OpPower {
+			result = power(left, right)
+		}
+
+		if result != nil && result.Kind() == constant.Unknown {
+			return nil, badConstant(e, "the result is not a finite number")
+		}
+		if result != nil {
+			return result, nil
+		}
+	}
+
+	if e.Operator == ast.OpPlus && left.Kind() == constant.String && right.Kind() == constant.String {
+		return constant.BinaryOp(left, gotoken.ADD, right), nil
+	}
+
+	return nil, badConstant(e, "%s cannot be applied to %s and %s", e.Operator, describe(left), describe(right))
+}
+
+// spells a value in a message, numbers the way the language prints them and strings quoted
+func describe(value constant.Value) string {
+	if isNumber(value) {
+		return numfmt.Constant(value)
+	}
+	return value.ExactString()
+}
+
+func isNumber(value constant.Value) bool {
+	return value.Kind() == constant.Int || value.Kind() == constant.Float
+}
+
+// numbers and strings are ordered, booleans can only be tested for equality
+func comparable(left constant.Value, op gotoken.Token, right constant.Value) bool {
+	switch {
+	case isNumber(left) && isNumber(right):
+		return true
+	case left.Kind() == constant.String && right.Kind() == constant.String:
+		return true
+	case left.Kind() == constant.Bool && right.Kind() == constant.Bool:
+		return op == gotoken.EQL || op == gotoken.NEQ
+	}
+	return false
+}
+
+// the remainder takes the sign of the dividend, like C's fmod
+func modulo(left, right constant.Value) constant.Value {
+	if left.Kind() == constant.Int && right.Kind() == constant.Int {
+		return constant.BinaryOp(left, gotoken.REM, right)
+	}
+	l, _ := constant.Float64Val(left)
+	r, _ := constant.Float64Val(right)
+	return constant.MakeFloat64(math.Mod(l, r))
+}
+
+// exact for small non-negative integer exponents, float64 otherwise
+func power(base, exponent constant.Value) constant.Value {
+	if n, exact := constant.Int64Val(constant.ToInt(exponent)); exact && n >= 0 && n <= maxExactExponent {
+		result := constant.MakeInt64(1)
+		for i := int64(0); i < n; i++ {
+			result = constant.BinaryOp(result, gotoken.MUL, base)
+		}
+		return result
+	}
+	b, _ := constant.Float64Val(base)
+	x, _ := constant.Float64Val(exponent)
+	return constant.MakeFloat64(math.Pow(b, x))
+}
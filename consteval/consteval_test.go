@@ -0,0 +1,201 @@
+package consteval
+
+import (
+	"go/constant"
+	"llvm-lang/diagnostic"
+	"llvm-lang/parser"
+	"testing"
+)
+
+// folds src, which must parse cleanly
+func check(t *testing.T, src string) (map[string]constant.Value, []diagnostic.Diagnostic) {
+	t.Helper()
+	program, diags, _ := parser.Parse(nil, []byte(src))
+	if len(diags) > 0 {
+		t.Fatalf("%q doesn't parse: %v", src, diags)
+	}
+	return Check(program)
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // the folded value of const C = expr, as ExactString spells it
+	}{
+		// exact arithmetic
+		{"1 + 2 * 3", "7"},
+		{"0.1 + 0.2", "3/10"},
+		{"0.1 + 0.2 == 0.3", "true"},
+		{"1e2", "100"},
+		{"0x10 + 0b11 + 0o7", "26"},
+		{"-(4 ** 2) % 3", "-1"},
+
+		// integer division truncates toward zero, anything with a float in it doesn't
+		{"7 / 2", "3"},
+		{"-7 / 2", "-3"},
+		{"7 / -2", "-3"},
+		{"7.0 / 2", "7/2"},
+		{"(7 as float) / 2", "7/2"},
+		{"1 / 3 * 3", "0"},
+
+		// the remainder takes the sign of the dividend
+		{"7 % 3", "1"},
+		{"-7 % 3", "-1"},
+		{"7 % -3", "1"},
+		{"7.5 % 2", "3/2"},
+		{"-7.5 % 2", "-3/2"},
+
+		// ** is right associative, exact up to the exponent limit and float64 past it
+		{"2 ** 3 ** 2", "512"},
+		{"2 ** 10", "1024"},
+		{"-2 ** 2", "-4"},
+		{"2 ** -1", "1/2"},
+		{"2 ** 0.5 > 1.414 && 2 ** 0.5 < 1.415", "true"},
+		{"2 ** 1024 == 2 ** 512 * 2 ** 512", "true"},
+		{"10.0 ** 400 == 100 ** 200", "true"},
+		{"1.5 ** 1025 > 1e180", "true"},
+
+		// && and || skip their right side once the left decides, even when it isn't constant
+		{"true && false", "false"},
+		{"false && undefined", "false"},
+		{"true || undefined", "true"},
+		{"!false && 1 < 2", "true"},
+
+		// comparisons
+		{"\"ab\" < \"b\"", "true"},
+		{"true != false", "true"},
+		{"1 == 1.0", "true"},
+
+		// strings, casts, conditionals and sequences
+		{"\"con\" + \"cat\"", "\"concat\""},
+		{"3.9 as int", "3"},
+		{"-3.9 as int", "-3"},
+		{"3 as float", "3"},
+		{"1 < 2 ? \"yes\" : undefined", "\"yes\""},
+		{"(1; 2; 3)", "3"},
+	}
+	for _, test := range tests {
+		values, diags := check(t, "const C = "+test.expr+";")
+		if len(diags) > 0 {
+			t.Errorf("%s: %v", test.expr, diags)
+			continue
+		}
+		if got := values["C"].ExactString(); got != test.want {
+			t.Errorf("%s folded to %s, want %s", test.expr, got, test.want)
+		}
+	}
+}
+
+// later constants and asserts see the values of earlier ones
+func TestFoldUsesEarlierConstants(t *testing.T) {
+	values, diags := check(t, "const A = 2; const B = A * 3; const C = B / A + A;")
+	if len(diags) > 0 {
+		t.Fatal(diags)
+	}
+	for name, want := range map[string]int64{"A": 2, "B": 6, "C": 5} {
+		if got, _ := constant.Int64Val(values[name]); got != want {
+			t.Errorf("%s = %s, want %d", name, values[name], want)
+		}
+	}
+}
+
+func TestNotFolded(t *testing.T) {
+	tests := []struct {
+		src     string
+		code    string
+		message string
+	}{
+		// E0300, the initializer needs something only known at run time
+		{"let x = 1; const C = x + 1;", codeNotConstant, "initializer of C is not constant: x, x is not a constant"},
+		{"const C = f(1);", codeNotConstant, "initializer of C is not constant: f(1), it can only be computed at run time"},
+		{"const C = false || undefined;", codeNotConstant, "initializer of C is not constant: undefined, undefined is not a constant"},
+		{"const C = (1; y);", codeNotConstant, "initializer of C is not constant: y, y is not a constant"},
+
+		// E0301, every part is constant but they don't combine
+		{"const C = 1 / 0;", codeBadConstant, "initializer of C cannot be folded: (1 / 0), division by zero"},
+		{"const C = 1.5 % 0;", codeBadConstant, "initializer of C cannot be folded: (1.5 % 0), division by zero"},
+		{"const C = 2 ** 1025;", codeBadConstant, "initializer of C cannot be folded: (2 ** 1025), the result is not a finite number"},
+		{"const C = 10.0 ** 2000.5;", codeBadConstant, "initializer of C cannot be folded: (10 ** 2000.5), the result is not a finite number"},
+		{"const C = 1 + \"a\";", codeBadConstant, `initializer of C cannot be folded: (1 + "a"), + cannot be applied to 1 and "a"`},
+		{"const C = -\"a\";", codeBadConstant, `initializer of C cannot be folded: (-"a"), - cannot be applied to "a"`},
+		{"const C = true < false;", codeBadConstant, "initializer of C cannot be folded: (true < false), < cannot be applied to true and false"},
+		{"const C = 1 && true;", codeBadConstant, "initializer of C cannot be folded: 1, 1 is not a boolean"},
+		{"const C = \"a\" as int;", codeBadConstant, `initializer of C cannot be folded: ("a" as int), "a" cannot be converted to int`},
+	}
+	for _, test := range tests {
+		values, diags := check(t, test.src)
+		if len(diags) != 1 {
+			t.Errorf("%s: want one diagnostic, got %v", test.src, diags)
+			continue
+		}
+		if diags[0].Code != test.code || diags[0].Message != test.message {
+			t.Errorf("%s:\ngot  %s %s\nwant %s %s", test.src, diags[0].Code, diags[0].Message, test.code, test.message)
+		}
+		if _, ok := values["C"]; ok {
+			t.Errorf("%s: C has a value", test.src)
+		}
+	}
+}
+
+// a constant that didn't fold is reported once, where it is declared, not again where it is used
+func TestNotFoldedIsReportedOnce(t *testing.T) {
+	_, diags := check(t, "const A = 1 / 0; const B = A + 1;")
+	if len(diags) != 2 || diags[0].Code != codeBadConstant || diags[1].Code != codeNotConstant {
+		t.Fatalf("got %v", diags)
+	}
+	if want := "initializer of B is not constant: A, A has no constant value"; diags[1].Message != want {
+		t.Errorf("got %q, want %q", diags[1].Message, want)
+	}
+}
+
+// folding doesn't depend on where the constant sits
+func TestFoldInsideFunction(t *testing.T) {
+	values, diags := check(t, "def f() { const C = 6 / 4; C }")
+	if len(diags) > 0 {
+		t.Fatal(diags)
+	}
+	if got := values["C"]; got == nil || got.ExactString() != "1" {
+		t.Errorf("C = %v, want 1", got)
+	}
+}
+
+func TestStaticAssert(t *testing.T) {
+	tests := []struct {
+		src     string
+		code    string // empty when the assertion holds
+		message string
+	}{
+		{`const A = 7 / 2; static_assert(A == 3, "int div");`, "", ""},
+		{`static_assert(0.1 + 0.2 == 0.3);`, "", ""},
+		{`static_assert(false || 1 < 2, "short circuit");`, "", ""},
+
+		// E0302, the message is the one given or else the condition as written
+		{`static_assert(1 == 2, "one is not two");`, codeAssertFailed, "static assertion failed: one is not two"},
+		{`const A = 1; static_assert(A > 1);`, codeAssertFailed, "static assertion failed: (A > 1)"},
+
+		// E0303, part of the condition is only known at run time
+		{`let x = 1; static_assert(x == 1);`, codeAssertNotConstant, "static assertion is not constant: x, x is not a constant"},
+		{`static_assert(f(), "calls");`, codeAssertNotConstant, "static assertion is not constant: f(), it can only be computed at run time"},
+
+		// E0304, the condition or message is constant but doesn't fold to what an assertion needs
+		{`static_assert(1 / 0 == 1);`, codeAssertBadConstant, "static assertion cannot be folded: (1 / 0), division by zero"},
+		{`static_assert(1 + 1);`, codeAssertBadConstant, "static assertion cannot be folded: (1 + 1), (1 + 1) is not a boolean"},
+		{`static_assert(true, 42);`, codeAssertBadConstant, "static assertion cannot be folded: 42, the message 42 is not a string"},
+	}
+	for _, test := range tests {
+		_, diags := check(t, test.src)
+		if test.code == "" {
+			if len(diags) > 0 {
+				t.Errorf("%s: %v", test.src, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 {
+			t.Errorf("%s: want one diagnostic, got %v", test.src, diags)
+			continue
+		}
+		if diags[0].Code != test.code || diags[0].Message != test.message {
+			t.Errorf("%s:\ngot  %s %s\nwant %s %s", test.src, diags[0].Code, diags[0].Message, test.code, test.message)
+		}
+	}
+}
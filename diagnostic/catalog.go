@@ -0,0 +1,121 @@
+package diagnostic
+
+import (
+	"fmt"
+	"llvm-lang/token"
+	"sync"
+)
+
+// A Catalog maps diagnostic codes to fmt templates in one language
+type Catalog map[string]string
+
+const DefaultLocale = "en"
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]Catalog{
+		DefaultLocale: {
+			"E0000": "too many errors, stopped after %d",
+
+			// lexer
+			"E0001": "illegal character %q",
+			"E0002": "unterminated string literal",
+			"E0003": "unterminated raw string literal",
+			"E0004": "unterminated byte string literal",
+			"E0005": "unterminated heredoc, expected a line holding just %s",
+			"E0006": "heredoc tag %s must end its line",
+			"E0007": "heredoc needs a tag after <<<",
+
+			// parser
+			"E0100": "Honk! internal parser error near %q: %v",
+			"E0101": "Honk! no prefix parse function for %s found",
+			"E0102": "Honk! expected next token to be %s, got %s instead",
+			"E0103": "Honk! malformed number literal %q",
+			"E0104": "Honk! comparisons and ranges cannot be chained, found %s after %s",
+			"E0105": "Honk! cannot assign to %s",
+			"E0106": "Honk! expected struct name before {, got %s instead",
+			"E0107": "Honk! expression too deeply nested (limit is %d)",
+			"E0108": "Honk! expected a pattern, got %s instead",
+			"E0109": "Honk! unterminated ${ in string literal",
+			"E0110": "Honk! static_assert takes a condition and an optional message, got %d arguments",
+			"E0111": "Honk! %s is a reserved word and cannot be used here",
+			"E0112": "Honk! only named function definitions can be declared pure",
+			"E0113": "Honk! attributes go on named function and struct declarations, not %s",
+			"E0114": "Honk! invalid escape %s in byte string",
+
+			// semantic checks
+			"E0200": "cannot assign to constant %s",
+			"E0201": "%s is not declared, declare it with let first",
+			"E0202": "%s is declared pure but %s",
+			"E0203": "bad attribute @%s: %v",
+			"E0204": "@%s and @%s cannot be used together",
+			"E0205": "case values must be integer or string literals, got %s",
+			"E0206": "duplicate case %s, the first is at %s",
+			"E0207": "switch has more than one default, the first is at %s",
+			"E0208": "%s is empty but cases never fall through, list values together as in case 1, 2 instead",
+			"E0209": "@export name %s is already used by %s",
+			"W0200": "%s is declared again in the same scope",
+			"W0208": "%s is deprecated%s",
+
+			// constant evaluation
+			"E0300": "initializer of %s is not constant: %s, %s",
+			"E0301": "initializer of %s cannot be folded: %s, %s",
+			"E0302": "static assertion failed: %s",
+			"E0303": "static assertion is not constant: %s, %s",
+			"E0304": "static assertion cannot be folded: %s, %s",
+
+			// lint
+			"W0201": "%s is assigned but never used",
+			"W0202": "parameter %s is never used",
+			"W0203": "%s shadows the %s of the same name at %s",
+			"W0204": "condition is always the same, it only involves constants",
+			"W0205": "result of %s is discarded",
+			"W0206": "bad %s call: %s",
+			"W0207": "%s is not defined, did you mean %s?",
+		},
+	}
+)
+
+// RegisterCatalog adds or replaces the templates for locale. Codes missing from it fall back to DefaultLocale.
+func RegisterCatalog(locale string, catalog Catalog) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalogs[locale] = catalog
+}
+
+// Message renders code with args in locale, falling back to DefaultLocale and then to a bare listing of the
+// arguments so an unknown code still says something
+func Message(locale string, code string, args ...interface{}) string {
+	catalogsMu.RLock()
+	template, ok := catalogs[locale][code]
+	if !ok {
+		template, ok = catalogs[DefaultLocale][code]
+	}
+	catalogsMu.RUnlock()
+
+	if !ok {
+		return fmt.Sprint(args...)
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// New builds an error whose message comes from the catalog, so it can be re-rendered later with Localize
+func New(pos token.Position, code string, args ...interface{}) Diagnostic {
+	return Diagnostic{Pos: pos, Severity: Error, Code: code, Message: Message(DefaultLocale, code, args...), Args: args}
+}
+
+// Warn is New for warnings
+func Warn(pos token.Position, code string, args ...interface{}) Diagnostic {
+	diag := New(pos, code, args...)
+	diag.Severity = Warning
+	return diag
+}
+
+// Localize re-renders the message in locale. Diagnostics made with Errorf have no template and are returned as is.
+func (d Diagnostic) Localize(locale string) Diagnostic {
+	if d.Args == nil {
+		return d
+	}
+	d.Message = Message(locale, d.Code, d.Args...)
+	return d
+}
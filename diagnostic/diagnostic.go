@@ -0,0 +1,69 @@
+package diagnostic
+
+import (
+	"fmt"
+	"llvm-lang/token"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// A Diagnostic is a single problem found in a source file by any stage of the compiler
+type Diagnostic struct {
+	Pos      token.Position
+	Length   int // how many bytes from Pos the problem spans, zero for just the one at Pos
+	Severity Severity
+	Code     string // stable identifier like E0101, what suppressions and tests should match on
+	Message  string
+	Args     []interface{} // what Message was rendered from, nil for free-form messages
+	Fixes    []Fix         // ways to resolve it mechanically, the likeliest first
+	Notes    []Note
+}
+
+// A Note points at something else in the source that explains a diagnostic, like the earlier definition a
+// redefinition clashes with
+type Note struct {
+	Pos     token.Position
+	Length  int
+	Message string
+}
+
+// A Fix is a set of edits that resolves a diagnostic, safe to apply without a person looking at it
+type Fix struct {
+	Message string // what applying it does, like "replace with count"
+	Edits   []Edit
+}
+
+// An Edit replaces Length bytes at Pos with NewText
+type Edit struct {
+	Pos     token.Position
+	Length  int
+	NewText string
+}
+
+func Errorf(pos token.Position, code string, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Pos: pos, Severity: Error, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (d Diagnostic) String() string {
+	if d.Code != "" {
+		return fmt.Sprintf("%s: %s[%s]: %s", d.Pos, d.Severity, d.Code, d.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
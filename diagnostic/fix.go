@@ -0,0 +1,40 @@
+package diagnostic
+
+import "sort"
+
+// ApplyFixes makes the first fix of each diagnostic in src, skipping any that would overlap an edit already
+// made, and returns the result along with the diagnostics whose fixes were not made
+func ApplyFixes(src []byte, diags []Diagnostic) ([]byte, []Diagnostic) {
+	edits := make([]Edit, 0)
+	unfixed := make([]Diagnostic, 0)
+
+	for _, diag := range diags {
+		if len(diag.Fixes) == 0 || overlaps(edits, diag.Fixes[0].Edits) {
+			unfixed = append(unfixed, diag)
+			continue
+		}
+		edits = append(edits, diag.Fixes[0].Edits...)
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Pos.Offset < edits[j].Pos.Offset })
+	out := make([]byte, 0, len(src))
+	last := 0
+	for _, edit := range edits {
+		out = append(out, src[last:edit.Pos.Offset]...)
+		out = append(out, edit.NewText...)
+		last = edit.Pos.Offset + edit.Length
+	}
+	return append(out, src[last:]...), unfixed
+}
+
+// two insertions at the same offset overlap too, since which goes first would be arbitrary
+func overlaps(made []Edit, edits []Edit) bool {
+	for _, a := range made {
+		for _, b := range edits {
+			if a.Pos.Offset < b.Pos.Offset+b.Length && b.Pos.Offset < a.Pos.Offset+a.Length || a.Pos.Offset == b.Pos.Offset {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package diagnostic
+
+// CodeTooManyErrors is the code of the error Limit puts in place of the errors it drops
+const CodeTooManyErrors = "E0000"
+
+// CountErrors returns how many of diags are errors, not counting one saying there were too many
+func CountErrors(diags []Diagnostic) int {
+	n := 0
+	for _, diag := range diags {
+		if diag.Severity == Error && diag.Code != CodeTooManyErrors {
+			n++
+		}
+	}
+	return n
+}
+
+// Limit cuts diags off after the max-th error, ending them instead with an error saying there were too many,
+// placed where the first error dropped was. Warnings before the cut are kept. diags is returned unchanged
+// when it has no more than max errors.
+func Limit(diags []Diagnostic, max int) []Diagnostic {
+	if CountErrors(diags) <= max {
+		return diags
+	}
+
+	limited := make([]Diagnostic, 0, max+1)
+	errors := 0
+	for _, diag := range diags {
+		if diag.Code == CodeTooManyErrors {
+			continue // from an earlier cut, replaced below
+		}
+		if diag.Severity == Error {
+			if errors == max {
+				return append(limited, New(diag.Pos, CodeTooManyErrors, max))
+			}
+			errors++
+		}
+		limited = append(limited, diag)
+	}
+	return limited
+}
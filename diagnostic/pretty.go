@@ -0,0 +1,168 @@
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"llvm-lang/token"
+	"strings"
+	"unicode/utf8"
+)
+
+// PrettyOptions configure WritePretty
+type PrettyOptions struct {
+	// Color adds ANSI escapes, for output going to a terminal
+	Color bool
+
+	// Source returns the text of the named file so its lines can be quoted. Diagnostics are printed without
+	// a quote when it is nil or returns nil.
+	Source func(filename string) []byte
+}
+
+// ANSI escapes, bold and then a color
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+	ansiCyan   = "\x1b[1;36m"
+)
+
+// WritePretty renders diags for people reading a terminal, quoting the line each one is on and underlining
+// the part at fault, then doing the same for each of its notes:
+//
+//	error[E0201]: undeclared name cuont
+//	 --> main.ll:3:5
+//	  |
+//	3 |     cuont = 1;
+//	  |     ^^^^^
+//
+// Suppressed diagnostics are rendered too, marked as such, so pass nil to leave them out.
+func WritePretty(w io.Writer, diags []Diagnostic, suppressed []Diagnostic, options PrettyOptions) error {
+	r := &prettyWriter{options: options, files: make(map[string]*token.File)}
+	for _, diag := range diags {
+		r.diagnostic(diag, false)
+	}
+	for _, diag := range suppressed {
+		r.diagnostic(diag, true)
+	}
+	_, err := io.WriteString(w, r.out.String())
+	return err
+}
+
+type prettyWriter struct {
+	options PrettyOptions
+	out     strings.Builder
+	files   map[string]*token.File // line tables for the sources quoted so far
+}
+
+func (r *prettyWriter) paint(color, text string) string {
+	if !r.options.Color {
+		return text
+	}
+	return color + text + ansiReset
+}
+
+func (r *prettyWriter) diagnostic(diag Diagnostic, isSuppressed bool) {
+	color := ansiCyan
+	switch diag.Severity {
+	case Error:
+		color = ansiRed
+	case Warning:
+		color = ansiYellow
+	}
+
+	header := diag.Severity.String()
+	if diag.Code != "" {
+		header += "[" + diag.Code + "]"
+	}
+	r.out.WriteString(r.paint(color, header) + r.paint(ansiBold, ": "+diag.Message))
+	if isSuppressed {
+		r.out.WriteString(" (suppressed)")
+	}
+	r.out.WriteString("\n")
+	r.quote(diag.Pos, diag.Length, "^", color)
+
+	for _, note := range diag.Notes {
+		if note.Pos.Line == 0 {
+			r.out.WriteString("  " + r.paint(ansiBlue, "=") + " " + r.paint(ansiBold, "note") + ": " + note.Message + "\n")
+			continue
+		}
+		r.out.WriteString(r.paint(ansiCyan, "note") + ": " + note.Message + "\n")
+		r.quote(note.Pos, note.Length, "-", ansiCyan)
+	}
+}
+
+// writes where pos is, then its line with length bytes from it underlined by mark, or just the location when
+// the source can't be had
+func (r *prettyWriter) quote(pos token.Position, length int, mark string, color string) {
+	line, ok := r.line(pos)
+	gutter := strings.Repeat(" ", len(fmt.Sprint(pos.Line)))
+	r.out.WriteString(gutter + r.paint(ansiBlue, "--> ") + pos.String() + "\n")
+	if !ok {
+		return
+	}
+
+	// the byte in line pos is at, from its offset rather than its column since tabs may widen columns
+	start := r.files[pos.Filename].LineStart(pos.Line)
+	at := pos.Offset - start
+	if at < 0 || at > len(line) {
+		at = pos.Column - 1
+	}
+	if at < 0 || at > len(line) {
+		return
+	}
+	end := at + length
+	if length <= 0 {
+		end = at + 1
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+
+	// the padding copies the line's tabs so the underline stays aligned however wide the terminal shows them
+	var padding strings.Builder
+	for _, c := range line[:at] {
+		if c == '\t' {
+			padding.WriteByte('\t')
+		} else {
+			padding.WriteByte(' ')
+		}
+	}
+	width := utf8.RuneCountInString(line[at:end])
+	if width == 0 {
+		width = 1 // at the end of the line, underline where the missing text would go
+	}
+
+	bar := r.paint(ansiBlue, "|")
+	r.out.WriteString(gutter + " " + bar + "\n")
+	r.out.WriteString(r.paint(ansiBlue, fmt.Sprint(pos.Line)) + " " + bar + " " + line + "\n")
+	r.out.WriteString(gutter + " " + bar + " " + padding.String() + r.paint(color, strings.Repeat(mark, width)) + "\n")
+}
+
+// the text of the line pos is on, without its line break
+func (r *prettyWriter) line(pos token.Position) (string, bool) {
+	if r.options.Source == nil || pos.Line <= 0 {
+		return "", false
+	}
+	source := r.options.Source(pos.Filename)
+	if source == nil {
+		return "", false
+	}
+
+	file, ok := r.files[pos.Filename]
+	if !ok {
+		file = token.NewFile(pos.Filename, source)
+		r.files[pos.Filename] = file
+	}
+	if pos.Line > file.LineCount() {
+		return "", false
+	}
+
+	start := file.LineStart(pos.Line)
+	end := len(source)
+	if pos.Line < file.LineCount() {
+		end = file.LineStart(pos.Line+1) - 1
+	}
+	return strings.TrimSuffix(string(source[start:end]), "\r"), true
+}
@@ -0,0 +1,284 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// The formats Write can render in. Text is for people, JSON and SARIF for editors and CI systems.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// Write renders diags in format. Suppressed diagnostics are rendered too, marked as such, so pass nil to
+// leave them out.
+func Write(w io.Writer, format string, diags []Diagnostic, suppressed []Diagnostic) error {
+	switch format {
+	case FormatText:
+		return writeText(w, diags, suppressed)
+	case FormatJSON:
+		return writeJSON(w, toJSON(diags, suppressed))
+	case FormatSARIF:
+		return writeJSON(w, toSARIF(diags, suppressed))
+	}
+	return fmt.Errorf("unknown diagnostic format %q, want text, json or sarif", format)
+}
+
+func writeText(w io.Writer, diags []Diagnostic, suppressed []Diagnostic) error {
+	for _, diag := range diags {
+		if _, err := fmt.Fprintln(w, diag); err != nil {
+			return err
+		}
+	}
+	for _, diag := range suppressed {
+		if _, err := fmt.Fprintf(w, "%s (suppressed)\n", diag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false) // names like <stdin> are not HTML
+	return encoder.Encode(v)
+}
+
+type jsonDiagnostic struct {
+	File       string     `json:"file,omitempty"`
+	Line       int        `json:"line"`
+	Column     int        `json:"column"`
+	Offset     int        `json:"offset"`
+	Length     int        `json:"length,omitempty"`
+	Severity   string     `json:"severity"`
+	Code       string     `json:"code,omitempty"`
+	Message    string     `json:"message"`
+	Fixes      []jsonFix  `json:"fixes,omitempty"`
+	Notes      []jsonNote `json:"notes,omitempty"`
+	Suppressed bool       `json:"suppressed,omitempty"`
+}
+
+type jsonNote struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length,omitempty"`
+	Message string `json:"message"`
+}
+
+type jsonFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits"`
+}
+
+type jsonEdit struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+	NewText string `json:"newText"`
+}
+
+func toJSON(diags []Diagnostic, suppressed []Diagnostic) []jsonDiagnostic {
+	out := make([]jsonDiagnostic, 0, len(diags)+len(suppressed))
+	add := func(diag Diagnostic, isSuppressed bool) {
+		fixes := make([]jsonFix, 0, len(diag.Fixes))
+		for _, fix := range diag.Fixes {
+			edits := make([]jsonEdit, 0, len(fix.Edits))
+			for _, edit := range fix.Edits {
+				edits = append(edits, jsonEdit{File: edit.Pos.Filename, Line: edit.Pos.Line, Column: edit.Pos.Column, Offset: edit.Pos.Offset, Length: edit.Length, NewText: edit.NewText})
+			}
+			fixes = append(fixes, jsonFix{Message: fix.Message, Edits: edits})
+		}
+		notes := make([]jsonNote, 0, len(diag.Notes))
+		for _, note := range diag.Notes {
+			notes = append(notes, jsonNote{File: note.Pos.Filename, Line: note.Pos.Line, Column: note.Pos.Column, Offset: note.Pos.Offset, Length: note.Length, Message: note.Message})
+		}
+		out = append(out, jsonDiagnostic{
+			File: diag.Pos.Filename, Line: diag.Pos.Line, Column: diag.Pos.Column, Offset: diag.Pos.Offset, Length: diag.Length,
+			Severity: diag.Severity.String(), Code: diag.Code, Message: diag.Message, Fixes: fixes, Notes: notes, Suppressed: isSuppressed,
+		})
+	}
+
+	for _, diag := range diags {
+		add(diag, false)
+	}
+	for _, diag := range suppressed {
+		add(diag, true)
+	}
+	return out
+}
+
+// SARIF 2.1.0, only the parts CI systems read: one run, a rule per code, a result per diagnostic
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID           string             `json:"ruleId,omitempty"`
+	Level            string             `json:"level"`
+	Message          sarifMessage       `json:"message"`
+	Locations        []sarifLocation    `json:"locations"`
+	RelatedLocations []sarifLocation    `json:"relatedLocations,omitempty"` // the notes
+	Fixes            []sarifFix         `json:"fixes,omitempty"`
+	Suppressions     []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int  `json:"startLine"`
+	StartColumn int  `json:"startColumn"`
+	CharOffset  *int `json:"charOffset,omitempty"` // only for replacements, which need the length too
+	CharLength  *int `json:"charLength,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+type sarifSuppression struct {
+	Kind string `json:"kind"`
+}
+
+func toSARIF(diags []Diagnostic, suppressed []Diagnostic) sarifLog {
+	results := make([]sarifResult, 0, len(diags)+len(suppressed))
+	codes := make(map[string]bool)
+
+	add := func(diag Diagnostic, isSuppressed bool) {
+		if diag.Code != "" {
+			codes[diag.Code] = true
+		}
+
+		result := sarifResult{
+			RuleID:  diag.Code,
+			Level:   sarifLevel(diag.Severity),
+			Message: sarifMessage{Text: diag.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: diag.Pos.Filename},
+				Region:           sarifRegion{StartLine: diag.Pos.Line, StartColumn: diag.Pos.Column},
+			}}},
+		}
+		for _, note := range diag.Notes {
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: note.Pos.Filename},
+					Region:           sarifRegion{StartLine: note.Pos.Line, StartColumn: note.Pos.Column},
+				},
+				Message: &sarifMessage{Text: note.Message},
+			})
+		}
+		for _, fix := range diag.Fixes {
+			result.Fixes = append(result.Fixes, toSARIFFix(fix))
+		}
+		if isSuppressed {
+			result.Suppressions = []sarifSuppression{{Kind: "inSource"}}
+		}
+		results = append(results, result)
+	}
+
+	for _, diag := range diags {
+		add(diag, false)
+	}
+	for _, diag := range suppressed {
+		add(diag, true)
+	}
+
+	rules := make([]sarifRule, 0, len(codes))
+	for code := range codes {
+		rules = append(rules, sarifRule{ID: code})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: sarifDriver{Name: "llvm-lang", Rules: rules}}, Results: results}},
+	}
+}
+
+// SARIF groups changes by file, edits to the same file share an artifactChange
+func toSARIFFix(fix Fix) sarifFix {
+	out := sarifFix{Description: sarifMessage{Text: fix.Message}, ArtifactChanges: make([]sarifArtifactChange, 0)}
+	changes := make(map[string]int) // file -> index in ArtifactChanges
+
+	for _, edit := range fix.Edits {
+		i, ok := changes[edit.Pos.Filename]
+		if !ok {
+			i = len(out.ArtifactChanges)
+			changes[edit.Pos.Filename] = i
+			out.ArtifactChanges = append(out.ArtifactChanges, sarifArtifactChange{ArtifactLocation: sarifArtifactLocation{URI: edit.Pos.Filename}})
+		}
+
+		offset, length := edit.Pos.Offset, edit.Length
+		region := sarifRegion{StartLine: edit.Pos.Line, StartColumn: edit.Pos.Column, CharOffset: &offset, CharLength: &length}
+		out.ArtifactChanges[i].Replacements = append(out.ArtifactChanges[i].Replacements, sarifReplacement{DeletedRegion: region, InsertedContent: sarifMessage{Text: edit.NewText}})
+	}
+	return out
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,54 @@
+package diagnostic
+
+import "sort"
+
+// Sort orders diagnostics by file, then position, then severity (errors first), then message,
+// so output is the same no matter which pass found what first
+func Sort(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		return less(diags[i], diags[j])
+	})
+}
+
+// Merge combines the diagnostics of several passes into one sorted slice
+func Merge(lists ...[]Diagnostic) []Diagnostic {
+	merged := make([]Diagnostic, 0)
+	for _, list := range lists {
+		merged = append(merged, list...)
+	}
+	Sort(merged)
+	return merged
+}
+
+// Unique drops each diagnostic identical to the one before it, so sorted diags keep one of every finding two
+// passes both made
+func Unique(diags []Diagnostic) []Diagnostic {
+	unique := make([]Diagnostic, 0, len(diags))
+	for _, diag := range diags {
+		if n := len(unique); n > 0 && same(unique[n-1], diag) {
+			continue
+		}
+		unique = append(unique, diag)
+	}
+	return unique
+}
+
+func same(a, b Diagnostic) bool {
+	return a.Pos == b.Pos && a.Severity == b.Severity && a.Code == b.Code && a.Message == b.Message
+}
+
+func less(a, b Diagnostic) bool {
+	if a.Pos.Filename != b.Pos.Filename {
+		return a.Pos.Filename < b.Pos.Filename
+	}
+	if a.Pos.Line != b.Pos.Line {
+		return a.Pos.Line < b.Pos.Line
+	}
+	if a.Pos.Column != b.Pos.Column {
+		return a.Pos.Column < b.Pos.Column
+	}
+	if a.Severity != b.Severity {
+		return a.Severity < b.Severity
+	}
+	return a.Message < b.Message
+}
@@ -0,0 +1,163 @@
+package diagnostic
+
+import (
+	"llvm-lang/token"
+	"reflect"
+	"testing"
+)
+
+// a diagnostic in file at line:column, with just enough else set to tell them apart
+func at(file string, line, column int, severity Severity, code, message string) Diagnostic {
+	return Diagnostic{
+		Pos:      token.Position{Filename: file, Line: line, Column: column},
+		Severity: severity,
+		Code:     code,
+		Message:  message,
+	}
+}
+
+func codes(diags []Diagnostic) []string {
+	codes := make([]string, len(diags))
+	for i, diag := range diags {
+		codes[i] = diag.Code
+	}
+	return codes
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name  string
+		diags []Diagnostic
+		want  []string // the codes in sorted order
+	}{
+		{
+			name:  "file",
+			diags: []Diagnostic{at("b.lang", 1, 1, Error, "B", "x"), at("a.lang", 9, 9, Error, "A", "x"), at("", 5, 5, Error, "none", "x")},
+			want:  []string{"none", "A", "B"},
+		},
+		{
+			name:  "line",
+			diags: []Diagnostic{at("a.lang", 10, 1, Error, "10", "x"), at("a.lang", 2, 7, Error, "2", "x"), at("a.lang", 1, 9, Error, "1", "x")},
+			want:  []string{"1", "2", "10"},
+		},
+		{
+			name:  "column",
+			diags: []Diagnostic{at("a.lang", 3, 12, Error, "12", "x"), at("a.lang", 3, 4, Error, "4", "x")},
+			want:  []string{"4", "12"},
+		},
+		{
+			name:  "errors before warnings",
+			diags: []Diagnostic{at("a.lang", 3, 4, Warning, "warning", "a"), at("a.lang", 3, 4, Error, "error", "z")},
+			want:  []string{"error", "warning"},
+		},
+		{
+			name:  "message",
+			diags: []Diagnostic{at("a.lang", 3, 4, Error, "second", "b"), at("a.lang", 3, 4, Error, "first", "a")},
+			want:  []string{"first", "second"},
+		},
+		{
+			// the code isn't part of the order, so these are equal and keep the order they came in
+			name:  "stable",
+			diags: []Diagnostic{at("a.lang", 3, 4, Error, "E2", "m"), at("a.lang", 1, 1, Error, "E0", "m"), at("a.lang", 3, 4, Error, "E1", "m"), at("a.lang", 3, 4, Error, "E3", "m")},
+			want:  []string{"E0", "E2", "E1", "E3"},
+		},
+		{
+			name:  "empty",
+			diags: []Diagnostic{},
+			want:  []string{},
+		},
+	}
+	for _, test := range tests {
+		Sort(test.diags)
+		if got := codes(test.diags); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: sorted to %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists [][]Diagnostic
+		want  []string
+	}{
+		{
+			name: "interleaved",
+			lists: [][]Diagnostic{
+				{at("a.lang", 1, 1, Error, "parse 1", "x"), at("a.lang", 4, 1, Error, "parse 4", "x")},
+				{at("a.lang", 2, 1, Warning, "sema 2", "x"), at("a.lang", 4, 1, Warning, "sema 4", "x")},
+				{at("a.lang", 3, 1, Warning, "lint 3", "x")},
+			},
+			want: []string{"parse 1", "sema 2", "lint 3", "parse 4", "sema 4"},
+		},
+		{
+			// equal diagnostics come out in the order of the lists they were in
+			name: "equal",
+			lists: [][]Diagnostic{
+				{at("a.lang", 1, 1, Error, "first", "x")},
+				{at("a.lang", 1, 1, Error, "second", "x")},
+			},
+			want: []string{"first", "second"},
+		},
+		{
+			name:  "one list",
+			lists: [][]Diagnostic{{at("a.lang", 1, 1, Error, "only", "x")}},
+			want:  []string{"only"},
+		},
+		{
+			name:  "nothing",
+			lists: [][]Diagnostic{nil, {}},
+			want:  []string{},
+		},
+	}
+	for _, test := range tests {
+		if got := codes(Merge(test.lists...)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: merged to %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// Merge hands back a slice of its own, the lists it was given stay as they were
+func TestMergeCopies(t *testing.T) {
+	list := []Diagnostic{at("a.lang", 2, 1, Error, "2", "x"), at("a.lang", 1, 1, Error, "1", "x")}
+	merged := Merge(list)
+	merged[0].Code = "changed"
+	if got := codes(list); !reflect.DeepEqual(got, []string{"2", "1"}) {
+		t.Errorf("the list changed to %v", got)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	d := at("a.lang", 1, 1, Error, "E0101", "message")
+	tests := []struct {
+		name  string
+		diags []Diagnostic
+		want  int // how many are left
+	}{
+		{"exact duplicates", []Diagnostic{d, d, d}, 1},
+		{"other severity", []Diagnostic{d, at("a.lang", 1, 1, Warning, "E0101", "message")}, 2},
+		{"other code", []Diagnostic{d, at("a.lang", 1, 1, Error, "E0102", "message")}, 2},
+		{"other message", []Diagnostic{d, at("a.lang", 1, 1, Error, "E0101", "other")}, 2},
+		{"other position", []Diagnostic{d, at("a.lang", 1, 2, Error, "E0101", "message")}, 2},
+		{"other file", []Diagnostic{d, at("b.lang", 1, 1, Error, "E0101", "message")}, 2},
+		// only neighbours are compared, so duplicates have to be sorted next to each other first
+		{"not adjacent", []Diagnostic{d, at("a.lang", 2, 1, Error, "E0101", "message"), d}, 3},
+		{"empty", nil, 0},
+	}
+	for _, test := range tests {
+		if got := Unique(test.diags); len(got) != test.want {
+			t.Errorf("%s: %d left, want %d", test.name, len(got), test.want)
+		}
+	}
+}
+
+// a finding two passes both made is left once after merging
+func TestMergeUnique(t *testing.T) {
+	parse := []Diagnostic{at("a.lang", 3, 1, Error, "E0101", "bad"), at("a.lang", 1, 1, Error, "E0101", "bad")}
+	sema := []Diagnostic{at("a.lang", 1, 1, Error, "E0101", "bad"), at("a.lang", 2, 1, Warning, "W0200", "unused")}
+	got := Unique(Merge(parse, sema))
+	want := []Diagnostic{parse[1], sema[1], parse[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package diagnostic
+
+import (
+	"llvm-lang/ast"
+	"strings"
+)
+
+// Suppressions records which diagnostic codes nolint comments have switched off:
+//
+//	x = y; // nolint:W0201,W0202    those codes, on this line only
+//	x = y; // nolint                every code, on this line only
+//	// nolint-file:W0201            that code, anywhere in the file
+//
+// Only warnings and infos can be suppressed, errors always get through.
+type Suppressions struct {
+	lines map[int]map[string]bool // line -> codes, an empty set means every code
+	file  map[string]bool
+	all   bool // a bare nolint-file
+}
+
+func NewSuppressions(comments []*ast.Comment) *Suppressions {
+	s := &Suppressions{lines: make(map[int]map[string]bool), file: make(map[string]bool)}
+
+	for _, comment := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+
+		directive, list, _ := strings.Cut(text, ":")
+		codes := parseCodes(list)
+
+		switch strings.TrimSpace(directive) {
+		case "nolint-file":
+			if len(codes) == 0 {
+				s.all = true
+			}
+			for code := range codes {
+				s.file[code] = true
+			}
+		case "nolint":
+			s.lines[comment.Pos.Line] = codes
+		}
+	}
+
+	return s
+}
+
+func parseCodes(list string) map[string]bool {
+	codes := make(map[string]bool)
+	for _, code := range strings.Split(list, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			codes[code] = true
+		}
+	}
+	return codes
+}
+
+// Suppressed reports whether a nolint comment covers diag
+func (s *Suppressions) Suppressed(diag Diagnostic) bool {
+	if diag.Severity == Error {
+		return false
+	}
+	if s.all || s.file[diag.Code] {
+		return true
+	}
+
+	codes, ok := s.lines[diag.Pos.Line]
+	return ok && (len(codes) == 0 || codes[diag.Code])
+}
+
+// Filter splits diags into the ones to report and the ones a nolint comment covers
+func (s *Suppressions) Filter(diags []Diagnostic) (kept []Diagnostic, suppressed []Diagnostic) {
+	kept = make([]Diagnostic, 0, len(diags))
+	suppressed = make([]Diagnostic, 0)
+
+	for _, diag := range diags {
+		if s.Suppressed(diag) {
+			suppressed = append(suppressed, diag)
+		} else {
+			kept = append(kept, diag)
+		}
+	}
+
+	return kept, suppressed
+}
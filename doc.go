@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"llvm-lang/diagnostic"
+	"llvm-lang/doc"
+	"llvm-lang/parser"
+	"llvm-lang/token"
+	"os"
+	"path/filepath"
+)
+
+// llvm-lang doc [file]
+func runDoc(args []string) int {
+	flags := flag.NewFlagSet("doc", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: llvm-lang doc [file]\n\nPrints Markdown documentation for the top-level declarations of file, reading from stdin when no file is given.\n")
+	}
+	flags.Parse(args)
+
+	source, err := readSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	name := flags.Arg(0)
+	if name == "" || name == "-" {
+		name = "<stdin>"
+	}
+
+	program, diags, _ := parser.Parse(token.NewFile(name, []byte(source)), []byte(source))
+	if report(source, diags, nil, false, diagnostic.DefaultLocale, diagnostic.FormatText) {
+		return 1
+	}
+
+	fmt.Print(doc.Markdown(program, filepath.Base(name)))
+	return 0
+}
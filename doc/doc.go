@@ -0,0 +1,41 @@
+// Package doc renders documentation for a program from the /// comments above its top-level declarations.
+package doc
+
+import (
+	"bytes"
+	"llvm-lang/ast"
+)
+
+// Markdown documents every top-level named function, struct and enum of program in source order, each under
+// a heading with its signature in a code block and its doc comment below
+func Markdown(program *ast.Program, title string) string {
+	var out bytes.Buffer
+
+	out.WriteString("# " + title + "\n")
+	for _, stmt := range program.Stmts {
+		var name, signature, doc string
+
+		switch s := stmt.(type) {
+		case *ast.StructDecl:
+			name, signature, doc = "struct "+s.Name.String(), s.String(), s.Doc
+		case *ast.EnumDecl:
+			name, signature, doc = "enum "+s.Name.String(), s.String(), s.Doc
+		case *ast.ExpressionStmt:
+			fn, ok := s.Expr.(*ast.FunctionLiteral)
+			if !ok || fn.Name == nil {
+				continue
+			}
+			name, signature, doc = "def "+fn.Name.String(), fn.Signature(), fn.Doc
+		default:
+			continue
+		}
+
+		out.WriteString("\n## " + name + "\n\n")
+		out.WriteString("```\n" + signature + "\n```\n")
+		if doc != "" {
+			out.WriteString("\n" + doc + "\n")
+		}
+	}
+
+	return out.String()
+}
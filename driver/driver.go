@@ -0,0 +1,93 @@
+// Package driver runs the front end in one call, for Go programs that embed the language and would rather not
+// wire the lexer, parser and analyses together themselves. There is no code generator or interpreter yet, so
+// checking is all it offers.
+package driver
+
+import (
+	"go/constant"
+	"llvm-lang/ast"
+	"llvm-lang/consteval"
+	"llvm-lang/diagnostic"
+	"llvm-lang/lint"
+	"llvm-lang/parser"
+	"llvm-lang/sema"
+	"llvm-lang/token"
+)
+
+type Options struct {
+	// Filename is shown in diagnostic positions, empty leaves it out
+	Filename string
+
+	// Files, when set, has the source added to it under Filename, for callers checking several files
+	Files *token.FileSet
+
+	// Lint runs the lint rules Config leaves enabled as well
+	Lint   bool
+	Config lint.Config
+
+	// MaxErrors stops at that many errors, with one more saying so. Zero means there is no limit.
+	MaxErrors int
+}
+
+type Result struct {
+	Program   *ast.Program
+	Constants map[string]constant.Value // the value of each const declaration that folded
+
+	// Diagnostics is in source order, without the warnings nolint comments switched off. Those are in
+	// Suppressed instead.
+	Diagnostics []diagnostic.Diagnostic
+	Suppressed  []diagnostic.Diagnostic
+}
+
+// Check parses source and, when it parses cleanly, checks its names, folds its constants and lints it. Each
+// analysis only runs on a program the ones before it found no errors in, since findings on a broken tree would
+// mostly be noise.
+func Check(source []byte, options Options) *Result {
+	var file *token.File
+	switch {
+	case options.Files != nil:
+		file = options.Files.AddFile(options.Filename, source)
+	case options.Filename != "":
+		file = token.NewFile(options.Filename, source)
+	}
+
+	result := &Result{Constants: make(map[string]constant.Value)}
+	program, diags, comments := parser.ParseWithOptions(file, source, parser.Options{MaxErrors: options.MaxErrors})
+	result.Program = program
+
+	if !hasErrors(diags) {
+		diags = append(diags, sema.Check(program)...)
+	}
+	if !hasErrors(diags) {
+		var folded []diagnostic.Diagnostic
+		result.Constants, folded = consteval.Check(program)
+		diags = append(diags, folded...)
+	}
+	if !hasErrors(diags) && options.Lint {
+		diags = append(diags, lint.Run(program, options.Config)...)
+	}
+
+	result.Diagnostics, result.Suppressed = diagnostic.NewSuppressions(comments).Filter(diags)
+	diagnostic.Sort(result.Diagnostics)
+	diagnostic.Sort(result.Suppressed)
+	result.Diagnostics = diagnostic.Unique(result.Diagnostics)
+	result.Suppressed = diagnostic.Unique(result.Suppressed)
+	if options.MaxErrors > 0 {
+		result.Diagnostics = diagnostic.Limit(result.Diagnostics, options.MaxErrors)
+	}
+	return result
+}
+
+// HasErrors reports whether any diagnostic is an error rather than a warning
+func (r *Result) HasErrors() bool {
+	return hasErrors(r.Diagnostics)
+}
+
+func hasErrors(diags []diagnostic.Diagnostic) bool {
+	for _, diag := range diags {
+		if diag.Severity == diagnostic.Error {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package driver
+
+import (
+	"bytes"
+	"flag"
+	"llvm-lang/ast"
+	"llvm-lang/diagnostic"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata from the current output")
+
+// TestGolden checks every testdata/*.lang against the .golden file beside it, which holds the program printed
+// back by String, its ast.Dump, and the diagnostics a linted Check reports. Run go test ./driver -update to
+// write the .golden files after changing the language on purpose, then review the diff.
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.lang"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no testdata/*.lang files")
+	}
+
+	for _, input := range inputs {
+		input := input
+		t.Run(strings.TrimSuffix(filepath.Base(input), ".lang"), func(t *testing.T) {
+			source, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := golden(filepath.Base(input), source)
+
+			path := strings.TrimSuffix(input, ".lang") + ".golden"
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v, run go test ./driver -update to create it", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s, run go test ./driver -update if that's intended\n--- got\n%s--- want\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+// the sections of a .golden file, each under a -- name -- line
+func golden(name string, source []byte) []byte {
+	result := Check(source, Options{Filename: name, Lint: true})
+
+	var out bytes.Buffer
+	out.WriteString("-- string --\n")
+	out.WriteString(result.Program.String() + "\n")
+	out.WriteString("-- ast --\n")
+	out.WriteString(ast.Dump(result.Program))
+	out.WriteString("-- diagnostics --\n")
+	diagnostic.Write(&out, diagnostic.FormatText, result.Diagnostics, result.Suppressed)
+	return out.Bytes()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"llvm-lang/diagnostic"
+	"os"
+)
+
+// makes the suggested fixes in the source read from path, writing the result back, or to stdout when the
+// source came from stdin. Returns the diagnostics left unfixed.
+func applyFixes(path string, source string, diags []diagnostic.Diagnostic) ([]diagnostic.Diagnostic, error) {
+	fixed, unfixed := diagnostic.ApplyFixes([]byte(source), diags)
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(fixed)
+		return unfixed, err
+	}
+
+	if len(unfixed) == len(diags) {
+		return unfixed, nil // nothing to write
+	}
+	if err := os.WriteFile(path, fixed, 0o644); err != nil {
+		return unfixed, err
+	}
+	fmt.Fprintf(os.Stderr, "made %d fixes in %s\n", len(diags)-len(unfixed), path)
+	return unfixed, nil
+}
@@ -0,0 +1,245 @@
+// Package genprog generates random programs for stress testing the parser and the analyses built on it.
+// Every program it produces parses cleanly and folds its constants, so any diagnostic other than a lint
+// warning points at a bug. The same options always give the same program.
+package genprog
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// how deeply for loops may nest, so the program can't grow without bound
+const maxBlockDepth = 3
+
+type Options struct {
+	Seed int64
+
+	// Statements is how many top-level statements to generate, function bodies get up to a quarter as many
+	Statements int
+
+	// MaxDepth bounds how deeply expressions nest, zero means 4
+	MaxDepth int
+}
+
+// Generate returns a program built from options
+func Generate(options Options) string {
+	if options.MaxDepth <= 0 {
+		options.MaxDepth = 4
+	}
+
+	g := &generator{
+		rand:    rand.New(rand.NewSource(options.Seed)),
+		options: options,
+		scopes:  [][]string{{}},
+		arity:   make(map[string]int),
+		fields:  make(map[string][]string),
+	}
+	for i := 0; i < options.Statements; i++ {
+		g.stmt(true)
+	}
+	return g.out.String()
+}
+
+type generator struct {
+	rand    *rand.Rand
+	options Options
+	out     strings.Builder
+	indent  int
+	names   int // numbers the names generated so far, keeping them unique
+
+	scopes    [][]string // variables and parameters visible in each enclosing scope, innermost last
+	constants []string   // only ever top level, like functions and structs
+	functions []string
+	arity     map[string]int // function -> parameter count
+	structs   []string
+	fields    map[string][]string
+}
+
+func (g *generator) name(prefix string) string {
+	g.names++
+	return fmt.Sprintf("%s%d", prefix, g.names)
+}
+
+func (g *generator) line(format string, args ...interface{}) {
+	g.out.WriteString(strings.Repeat("    ", g.indent))
+	g.out.WriteString(fmt.Sprintf(format, args...))
+	g.out.WriteString("\n")
+}
+
+func (g *generator) define(name string) {
+	g.scopes[len(g.scopes)-1] = append(g.scopes[len(g.scopes)-1], name)
+}
+
+func (g *generator) variables() []string {
+	all := make([]string, 0)
+	for _, scope := range g.scopes {
+		all = append(all, scope...)
+	}
+	return all
+}
+
+// pick returns one of names at random, or "" when there are none
+func (g *generator) pick(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[g.rand.Intn(len(names))]
+}
+
+func (g *generator) stmt(topLevel bool) {
+	switch n := g.rand.Intn(10); {
+	case n == 0 && topLevel:
+		g.constDecl()
+	case n == 1 && topLevel:
+		g.structDecl()
+	case n <= 3 && topLevel:
+		g.function()
+	case n == 4 && g.indent < maxBlockDepth:
+		g.forStmt()
+	case n <= 6:
+		name := g.name("v")
+		g.line("let %s = %s;", name, g.expr(0))
+		g.define(name)
+	default:
+		g.line("%s;", g.expr(0))
+	}
+}
+
+func (g *generator) constDecl() {
+	name := g.name("C")
+	g.line("const %s = %s;", name, g.constExpr(0))
+	g.constants = append(g.constants, name)
+}
+
+// constant expressions stay clear of division, so they always fold
+func (g *generator) constExpr(depth int) string {
+	if depth >= g.options.MaxDepth || g.rand.Intn(3) == 0 {
+		if c := g.pick(g.constants); c != "" && g.rand.Intn(2) == 0 {
+			return c
+		}
+		return g.number()
+	}
+	op := []string{"+", "-", "*"}[g.rand.Intn(3)]
+	return "(" + g.constExpr(depth+1) + " " + op + " " + g.constExpr(depth+1) + ")"
+}
+
+func (g *generator) structDecl() {
+	name := g.name("S")
+	fields := make([]string, 1+g.rand.Intn(3))
+	decls := make([]string, len(fields))
+	for i := range fields {
+		fields[i] = g.name("f")
+		decls[i] = fields[i] + ": float"
+	}
+	g.line("struct %s { %s }", name, strings.Join(decls, "; "))
+	g.structs = append(g.structs, name)
+	g.fields[name] = fields
+}
+
+func (g *generator) function() {
+	name := g.name("fn")
+	params := make([]string, g.rand.Intn(4))
+	for i := range params {
+		params[i] = g.name("p")
+	}
+	g.functions = append(g.functions, name) // before the body, so it may call itself
+	g.arity[name] = len(params)
+
+	g.line("def %s(%s) {", name, strings.Join(params, ", "))
+	g.block(params)
+	g.line("}")
+}
+
+func (g *generator) forStmt() {
+	name := g.name("i")
+	g.line("for %s in 0..%s {", name, g.number())
+	g.block([]string{name})
+	g.line("}")
+}
+
+// the statements of a block whose scope starts with names, ending in an expression for its value
+func (g *generator) block(names []string) {
+	g.scopes = append(g.scopes, names)
+	g.indent++
+
+	for i := g.rand.Intn(g.options.Statements/4 + 1); i > 0; i-- {
+		g.stmt(false)
+	}
+	g.line("%s", g.expr(0))
+
+	g.indent--
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+func (g *generator) number() string {
+	if g.rand.Intn(4) == 0 {
+		return fmt.Sprintf("%d.%d", g.rand.Intn(100), g.rand.Intn(100))
+	}
+	return fmt.Sprint(g.rand.Intn(100))
+}
+
+func (g *generator) expr(depth int) string {
+	if depth >= g.options.MaxDepth {
+		return g.leaf()
+	}
+
+	switch g.rand.Intn(12) {
+	case 0, 1, 2:
+		return g.leaf()
+	case 3:
+		return []string{"-", "!"}[g.rand.Intn(2)] + "(" + g.expr(depth+1) + ")" // parenthesized so - - never lexes as --
+	case 4, 5:
+		op := []string{"+", "-", "*", "/", "%", "==", "!=", "<", ">=", "&&", "||"}[g.rand.Intn(11)]
+		return "(" + g.expr(depth+1) + " " + op + " " + g.expr(depth+1) + ")"
+	case 6:
+		return "(" + g.expr(depth+1) + " ? " + g.expr(depth+1) + " : " + g.expr(depth+1) + ")"
+	case 7:
+		return "(" + g.expr(depth+1) + " as " + []string{"int", "float"}[g.rand.Intn(2)] + ")"
+	case 8:
+		if fn := g.pick(g.functions); fn != "" {
+			args := make([]string, g.arity[fn])
+			for i := range args {
+				args[i] = g.expr(depth + 1)
+			}
+			return fn + "(" + strings.Join(args, ", ") + ")"
+		}
+	case 9:
+		if s := g.pick(g.structs); s != "" {
+			fields := make([]string, 0)
+			for _, field := range g.fields[s] {
+				fields = append(fields, field+": "+g.expr(depth+1))
+			}
+			literal := s + " { " + strings.Join(fields, ", ") + " }"
+			if g.rand.Intn(2) == 0 {
+				return "(" + literal + ")." + g.pick(g.fields[s])
+			}
+			return literal
+		}
+	case 10:
+		param := g.name("x")
+		g.scopes = append(g.scopes, []string{param})
+		body := g.expr(depth + 1)
+		g.scopes = g.scopes[:len(g.scopes)-1]
+		return "(\\" + param + " -> " + body + ")"
+	case 11:
+		return "match " + g.leaf() + " { 0 => " + g.expr(depth+1) + ", _ => " + g.expr(depth+1) + " }"
+	}
+	return g.leaf()
+}
+
+func (g *generator) leaf() string {
+	switch g.rand.Intn(6) {
+	case 0, 1:
+		if v := g.pick(g.variables()); v != "" {
+			return v
+		}
+	case 2:
+		if c := g.pick(g.constants); c != "" {
+			return c
+		}
+	case 3:
+		return []string{"true", "false", "nil", `"s"`}[g.rand.Intn(4)]
+	}
+	return g.number()
+}
@@ -0,0 +1,42 @@
+package genprog_test
+
+import (
+	"flag"
+	"llvm-lang/diagnostic"
+	"llvm-lang/driver"
+	"llvm-lang/genprog"
+	"testing"
+)
+
+var (
+	seeds      = flag.Int("stress.seeds", 200, "how many programs each run of TestStress checks")
+	statements = flag.Int("stress.statements", 30, "how many top-level statements each TestStress program has")
+)
+
+// the first seed of the next TestStress run, so go test -run Stress -count 50 checks fresh programs each time
+// while any failure still names a seed that reproduces it
+var nextSeed int64
+
+// TestStress checks generated programs with every analysis on. They are all valid, so an error on any of them
+// is a bug in the generator or in the front end. Run it for longer with
+//
+//	go test ./genprog -run Stress -count 100 -stress.seeds 1000
+func TestStress(t *testing.T) {
+	first := nextSeed
+	nextSeed += int64(*seeds)
+
+	for seed := first; seed < first+int64(*seeds); seed++ {
+		options := genprog.Options{Seed: seed, Statements: *statements}
+		source := genprog.Generate(options)
+		if again := genprog.Generate(options); again != source {
+			t.Fatalf("seed %d generated two different programs", seed)
+		}
+
+		result := driver.Check([]byte(source), driver.Options{Filename: "stress.lang", Lint: true})
+		for _, diag := range result.Diagnostics {
+			if diag.Severity == diagnostic.Error {
+				t.Fatalf("seed %d: %s\n%s", seed, diag, source)
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package highlight classifies source text for syntax highlighting, straight from the lexer, so editors and
+// generated docs color code the same way the compiler reads it. Spans gives the classified pieces of a file,
+// HTML renders them for docs, and SemanticTokens encodes them for a language server.
+package highlight
+
+import (
+	"html"
+	"llvm-lang/lexer"
+	"llvm-lang/token"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Keyword Kind = iota
+	Identifier
+	Number
+	String // string, raw string, byte string and heredoc literals, apart from the expressions interpolated into them
+	Operator
+	Punctuation // brackets, separators and the @ of an attribute
+	Comment
+	Invalid // characters the lexer rejects
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Keyword:
+		return "keyword"
+	case Identifier:
+		return "identifier"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Operator:
+		return "operator"
+	case Punctuation:
+		return "punctuation"
+	case Comment:
+		return "comment"
+	default:
+		return "invalid"
+	}
+}
+
+// A Span is a piece of source of one Kind, from byte offset Start up to End. Spans never overlap, and the
+// whitespace between them belongs to none.
+type Span struct {
+	Kind  Kind
+	Start int
+	End   int
+}
+
+// Spans classifies source as the standard language, in source order
+func Spans(source string) []Span {
+	return SpansWithOptions(source, lexer.Options{})
+}
+
+// SpansWithOptions is Spans for a dialect, keywords are whatever options make them
+func SpansWithOptions(source string, options lexer.Options) []Span {
+	options.Newlines = false // line breaks are whitespace to a highlighter
+	return spans(lexer.NewWithOptions(source, options), options)
+}
+
+func spans(l *lexer.Lexer, options lexer.Options) []Span {
+	out := make([]Span, 0)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			return out
+		}
+		start, end := tok.Pos.Offset, l.End().Offset
+
+		if tok.Type != token.String {
+			out = append(out, Span{Kind: classify(l, tok), Start: start, End: end})
+			continue
+		}
+
+		// the text of an interpolated string is a string, the expressions inside ${...} are highlighted as code
+		segments, _ := l.Segments(tok)
+		for _, segment := range segments {
+			if !segment.Expr {
+				continue
+			}
+			out = append(out, Span{Kind: String, Start: start, End: segment.Pos.Offset})
+			out = append(out, spans(lexer.NewAt(segment.Text, segment.Pos, options), options)...)
+			start = segment.Pos.Offset + len(segment.Text)
+		}
+		if start < end {
+			out = append(out, Span{Kind: String, Start: start, End: end})
+		}
+	}
+}
+
+func classify(l *lexer.Lexer, tok token.Token) Kind {
+	switch tok.Type {
+	case token.Identifier:
+		return Identifier
+	case token.Number:
+		return Number
+	case token.String, token.RawString, token.Bytes, token.Heredoc:
+		return String
+	case token.Comment:
+		return Comment
+	case token.Illegal:
+		return Invalid
+	case token.LeftParen, token.RightParen, token.LeftCurlyBracket, token.RightCurlyBracket,
+		token.LeftSquareBracket, token.RightSquareBracket, token.Semicolon, token.Comma, token.Colon, token.At:
+		return Punctuation
+	}
+	if l.IsKeyword(tok.Literal) {
+		return Keyword
+	}
+	return Operator
+}
+
+// HTML renders source as HTML, each span wrapped in a <span> with its kind as class, like
+// <span class="keyword">def</span>. It doesn't add the surrounding <pre>.
+func HTML(source string) string {
+	var out strings.Builder
+
+	at := 0
+	for _, span := range Spans(source) {
+		out.WriteString(html.EscapeString(source[at:span.Start]))
+		out.WriteString(`<span class="` + span.Kind.String() + `">`)
+		out.WriteString(html.EscapeString(source[span.Start:span.End]))
+		out.WriteString("</span>")
+		at = span.End
+	}
+	out.WriteString(html.EscapeString(source[at:]))
+	return out.String()
+}
+
+// TokenTypes is the legend for SemanticTokens, a language server sends it to the client when they connect.
+// The numbers SemanticTokens encodes are indexes into it.
+var TokenTypes = []string{"keyword", "variable", "number", "string", "operator", "comment"}
+
+// index into TokenTypes by kind, punctuation and invalid characters are left to the editor's own grammar
+var tokenTypes = map[Kind]uint32{Keyword: 0, Identifier: 1, Number: 2, String: 3, Operator: 4, Comment: 5}
+
+// SemanticTokens encodes spans of source the way the LSP textDocument/semanticTokens request answers: five
+// numbers per token, its line and start relative to the token before, its length, its type and no modifiers.
+// Lines and characters are counted the LSP way, from zero and in UTF-16 code units. Spans running over several
+// lines, like raw strings, are split into one token per line, since not every client takes multiline tokens.
+func SemanticTokens(source string, spans []Span) []uint32 {
+	file := token.NewFile("", []byte(source))
+	data := make([]uint32, 0, len(spans)*5)
+
+	prevLine, prevChar := 0, 0
+	emit := func(start, end int, tokenType uint32) {
+		pos := file.Position(start)
+		line := pos.Line - 1
+		char := utf16Len(source[start-pos.Column+1 : start])
+		if line != prevLine {
+			prevChar = 0
+		}
+		data = append(data, uint32(line-prevLine), uint32(char-prevChar), uint32(utf16Len(source[start:end])), tokenType, 0)
+		prevLine, prevChar = line, char
+	}
+
+	for _, span := range spans {
+		tokenType, ok := tokenTypes[span.Kind]
+		if !ok {
+			continue
+		}
+		start := span.Start
+		for {
+			newline := strings.IndexByte(source[start:span.End], '\n')
+			if newline < 0 {
+				break
+			}
+			if newline > 0 {
+				emit(start, start+newline, tokenType)
+			}
+			start += newline + 1
+		}
+		if start < span.End {
+			emit(start, span.End, tokenType)
+		}
+	}
+	return data
+}
+
+// characters outside the Basic Multilingual Plane take two UTF-16 code units, everything else one
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r >= 0x10000 {
+			n++
+		}
+	}
+	return n
+}
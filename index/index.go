@@ -0,0 +1,391 @@
+// Package index resolves the identifiers of a parsed program to the symbols they name, recording each
+// symbol's definition and every reference to it. Editors use it for go-to-definition and find-references,
+// and analyses like rename and dead-code detection build on it.
+package index
+
+import (
+	"llvm-lang/ast"
+	"llvm-lang/token"
+	"sort"
+	"unicode/utf8"
+)
+
+type Kind int
+
+const (
+	Function Kind = iota
+	Parameter
+	Variable // introduced by let, for, or the first plain assignment to a name in its scope, which sema rejects
+	Binding  // introduced by a match pattern
+	Const
+	TypeParam
+	Struct
+	Enum
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Function:
+		return "function"
+	case Parameter:
+		return "parameter"
+	case Variable:
+		return "variable"
+	case Binding:
+		return "binding"
+	case Const:
+		return "constant"
+	case TypeParam:
+		return "type parameter"
+	case Struct:
+		return "struct"
+	default:
+		return "enum"
+	}
+}
+
+// A Symbol is one named thing the program defines
+type Symbol struct {
+	Name string
+	Kind Kind
+	Def  *ast.Identifier
+	Refs []*ast.Identifier // in source order
+
+	Shadows    *Symbol // the symbol of the same name from an enclosing scope this one hides, if any
+	Redeclares *Symbol // the symbol of the same name earlier in the same scope this one replaces, if any
+}
+
+type Index struct {
+	Symbols    []*Symbol // in order of definition
+	Unresolved []*ast.Identifier
+
+	symbols map[*ast.Identifier]*Symbol // both definitions and references
+	idents  []*ast.Identifier           // everything resolved, for lookups by position
+	scopes  map[*ast.Identifier]*scope  // where each unresolved identifier appears
+}
+
+// Build indexes program. Struct, enum and named function declarations are visible throughout the block that
+// declares them, so functions can call each other regardless of order; everything else is visible from its
+// definition on.
+func Build(program *ast.Program) *Index {
+	ix := &Index{Symbols: make([]*Symbol, 0), Unresolved: make([]*ast.Identifier, 0), symbols: make(map[*ast.Identifier]*Symbol), scopes: make(map[*ast.Identifier]*scope)}
+
+	b := &builder{index: ix, scope: newScope(nil)}
+	b.stmts(program.Stmts)
+
+	// assignments visit their value before their target, so references are found slightly out of order
+	for _, sym := range ix.Symbols {
+		sort.SliceStable(sym.Refs, func(i, j int) bool { return sym.Refs[i].Token.Pos.Offset < sym.Refs[j].Token.Pos.Offset })
+	}
+
+	return ix
+}
+
+// Lookup returns the symbol ident defines or refers to
+func (ix *Index) Lookup(ident *ast.Identifier) (*Symbol, bool) {
+	sym, ok := ix.symbols[ident]
+	return sym, ok
+}
+
+// Identifiers returns every identifier that defines or references a symbol, in the order they were resolved
+func (ix *Index) Identifiers() []*ast.Identifier {
+	return ix.idents
+}
+
+// At finds the resolved identifier covering pos, matching on file, line and column
+func (ix *Index) At(pos token.Position) (*ast.Identifier, *Symbol, bool) {
+	for _, ident := range ix.idents {
+		start := ident.Token.Pos
+		if start.Filename != pos.Filename || start.Line != pos.Line {
+			continue
+		}
+		if pos.Column >= start.Column && pos.Column < start.Column+utf8.RuneCountInString(ident.Value) {
+			return ident, ix.symbols[ident], true
+		}
+	}
+	return nil, nil, false
+}
+
+// Visible returns the symbols in scope where an unresolved identifier appears, in order of definition, for guessing
+// what it was meant to name. Symbols that are only visible from their definition on are left out if that comes
+// after the identifier.
+func (ix *Index) Visible(ident *ast.Identifier) []*Symbol {
+	visible := make([]*Symbol, 0)
+	seen := make(map[string]bool)
+	for s := ix.scopes[ident]; s != nil; s = s.parent {
+		for name, sym := range s.symbols {
+			if seen[name] {
+				continue // hidden by an inner symbol of the same name
+			}
+			seen[name] = true
+			if hoisted := sym.Kind == Function || sym.Kind == Struct || sym.Kind == Enum; !hoisted && sym.Def.Token.Pos.Offset > ident.Token.Pos.Offset {
+				continue
+			}
+			visible = append(visible, sym)
+		}
+	}
+	sort.SliceStable(visible, func(i, j int) bool { return visible[i].Def.Token.Pos.Offset < visible[j].Def.Token.Pos.Offset })
+	return visible
+}
+
+type scope struct {
+	parent  *scope
+	symbols map[string]*Symbol
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, symbols: make(map[string]*Symbol)}
+}
+
+func (s *scope) lookup(name string) (*Symbol, bool) {
+	for ; s != nil; s = s.parent {
+		if sym, ok := s.symbols[name]; ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+type builder struct {
+	index *Index
+	scope *scope
+}
+
+func (b *builder) define(ident *ast.Identifier, kind Kind) {
+	if ident == nil || ident.Value == "_" {
+		return
+	}
+
+	sym := &Symbol{Name: ident.Value, Kind: kind, Def: ident, Refs: make([]*ast.Identifier, 0)}
+	if b.scope.parent != nil {
+		sym.Shadows, _ = b.scope.parent.lookup(ident.Value)
+	}
+	sym.Redeclares = b.scope.symbols[ident.Value]
+	b.scope.symbols[ident.Value] = sym
+	b.index.Symbols = append(b.index.Symbols, sym)
+	b.index.symbols[ident] = sym
+	b.index.idents = append(b.index.idents, ident)
+}
+
+func (b *builder) reference(ident *ast.Identifier) {
+	if ident == nil {
+		return
+	}
+	if _, seen := b.index.symbols[ident]; seen {
+		return // a hoisted declaration's own name
+	}
+
+	sym, ok := b.scope.lookup(ident.Value)
+	if !ok {
+		b.index.Unresolved = append(b.index.Unresolved, ident)
+		b.index.scopes[ident] = b.scope
+		return
+	}
+	sym.Refs = append(sym.Refs, ident)
+	b.index.symbols[ident] = sym
+	b.index.idents = append(b.index.idents, ident)
+}
+
+// references a type name, names that aren't declared are taken to be builtin types rather than mistakes
+func (b *builder) typeReference(ident *ast.Identifier) {
+	if ident == nil {
+		return
+	}
+	if sym, ok := b.scope.lookup(ident.Value); ok && (sym.Kind == Struct || sym.Kind == Enum || sym.Kind == TypeParam) {
+		b.reference(ident)
+	}
+}
+
+func (b *builder) enter() {
+	b.scope = newScope(b.scope)
+}
+
+func (b *builder) leave() {
+	b.scope = b.scope.parent
+}
+
+func (b *builder) stmts(stmts []ast.Stmt) {
+	// hoist declarations first
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.StructDecl:
+			b.define(s.Name, Struct)
+		case *ast.EnumDecl:
+			b.define(s.Name, Enum)
+		case *ast.ExpressionStmt:
+			if fn, ok := s.Expr.(*ast.FunctionLiteral); ok && fn.Name != nil {
+				b.define(fn.Name, Function)
+			}
+		}
+	}
+
+	for _, stmt := range stmts {
+		b.stmt(stmt)
+	}
+}
+
+func (b *builder) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.ExpressionStmt:
+		b.expr(s.Expr)
+	case *ast.BlockStmt:
+		b.block(s)
+	case *ast.LetStmt:
+		b.expr(s.Value)
+		b.typeReference(s.Type)
+		b.define(s.Name, Variable)
+	case *ast.ConstDecl:
+		b.expr(s.Value)
+		b.define(s.Name, Const)
+	case *ast.StaticAssert:
+		b.expr(s.Condition)
+		b.expr(s.Message)
+	case *ast.TestDecl:
+		b.block(s.Body)
+	case *ast.SwitchStmt:
+		b.expr(s.Subject)
+		for _, c := range s.Cases {
+			for _, value := range c.Values {
+				b.expr(value)
+			}
+			b.block(c.Body)
+		}
+	case *ast.ForStmt:
+		b.expr(s.Iterable)
+		b.enter()
+		b.define(s.Var, Variable)
+		b.block(s.Body)
+		b.leave()
+	case *ast.StructDecl:
+		b.attributes(s.Attributes)
+		for _, field := range s.Fields {
+			b.typeReference(field.Type)
+		}
+	case *ast.EnumDecl:
+		for _, variant := range s.Variants {
+			for _, t := range variant.Payload {
+				b.typeReference(t)
+			}
+		}
+	}
+}
+
+func (b *builder) block(block *ast.BlockStmt) {
+	if block == nil {
+		return
+	}
+	b.enter()
+	b.stmts(block.Stmts)
+	b.leave()
+}
+
+func (b *builder) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		b.reference(e)
+	case *ast.InterpolatedString:
+		for _, part := range e.Parts {
+			b.expr(part)
+		}
+	case *ast.PrefixExpr:
+		b.expr(e.Right)
+	case *ast.PostfixExpr:
+		b.expr(e.Left)
+	case *ast.InfixExpr:
+		b.expr(e.Left)
+		b.expr(e.Right)
+	case *ast.AssignExpr:
+		b.expr(e.Value)
+		if target, isIdent := e.Target.(*ast.Identifier); isIdent && e.Operator == ast.OpAssign {
+			if _, defined := b.scope.lookup(target.Value); !defined {
+				b.define(target, Variable)
+				return
+			}
+		}
+		b.expr(e.Target)
+	case *ast.SequenceExpr:
+		for _, expr := range e.Exprs {
+			b.expr(expr)
+		}
+	case *ast.ConditionalExpr:
+		b.expr(e.Condition)
+		b.expr(e.Consequence)
+		b.expr(e.Alternative)
+	case *ast.RangeExpr:
+		b.expr(e.Start)
+		b.expr(e.End)
+	case *ast.CastExpr:
+		b.expr(e.Value)
+		b.typeReference(e.Type)
+	case *ast.MatchExpr:
+		b.expr(e.Subject)
+		for _, arm := range e.Arms {
+			b.enter()
+			b.pattern(arm.Pattern)
+			b.block(arm.Body)
+			b.leave()
+		}
+	case *ast.TryExpr:
+		b.block(e.Body)
+		b.enter()
+		b.define(e.Err, Binding)
+		b.block(e.Handler)
+		b.leave()
+	case *ast.CallExpr:
+		b.expr(e.Function)
+		for _, arg := range e.Arguments {
+			b.expr(arg)
+		}
+	case *ast.StructLiteral:
+		b.reference(e.Type)
+		for _, field := range e.Fields {
+			b.expr(field.Value)
+		}
+	case *ast.FieldAccessExpr:
+		b.expr(e.Object) // fields need types to resolve
+	case *ast.FunctionLiteral:
+		if _, hoisted := b.index.symbols[e.Name]; e.Name != nil && !hoisted {
+			b.define(e.Name, Function)
+		}
+		b.attributes(e.Attributes)
+		b.enter()
+		for _, t := range e.TypeParams {
+			b.define(t, TypeParam)
+		}
+		for _, param := range e.Parameters {
+			b.typeReference(param.Type)
+		}
+		b.typeReference(e.ReturnType)
+		for _, param := range e.Parameters {
+			b.define(param.Name, Parameter)
+		}
+		if e.Body != nil {
+			b.stmts(e.Body.Stmts)
+		}
+		b.leave()
+	}
+}
+
+// attribute names aren't symbols, but their arguments are ordinary expressions
+func (b *builder) attributes(attrs []*ast.Attribute) {
+	for _, attr := range attrs {
+		for _, arg := range attr.Args {
+			b.expr(arg)
+		}
+	}
+}
+
+// identifiers in a pattern bind new names, except for the enum in a variant like Shape.Circle(r)
+func (b *builder) pattern(pattern ast.Expr) {
+	switch p := pattern.(type) {
+	case *ast.Identifier:
+		b.define(p, Binding)
+	case *ast.FieldAccessExpr:
+		b.expr(p.Object)
+	case *ast.CallExpr:
+		b.pattern(p.Function)
+		for _, arg := range p.Arguments {
+			b.pattern(arg)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package irname checks and escapes names on their way into LLVM IR symbols, so the mangler and
+// @export("...") can't produce IR that fails to parse.
+package irname
+
+import (
+	"errors"
+	"fmt"
+	"llvm-lang/chars"
+	"strings"
+	"unicode/utf8"
+)
+
+// IsValid reports whether name can be written in IR without quotes: [-a-zA-Z$._][-a-zA-Z$._0-9]*
+func IsValid(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := rune(name[i])
+		switch {
+		case c == '-' || c == '$' || c == '.' || c == '_':
+		case c < utf8.RuneSelf && chars.IsIdentStart(c):
+		case i > 0 && chars.IsDigit(c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Quote returns name as-is when it is valid unquoted, otherwise wrapped in quotes with every byte
+// LLVM won't take literally written as \XX, so Unicode identifiers survive the trip byte for byte
+func Quote(name string) string {
+	if IsValid(name) {
+		return name
+	}
+
+	var out strings.Builder
+	out.WriteByte('"')
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < ' ' || c > '~' || c == '"' || c == '\\' {
+			fmt.Fprintf(&out, "\\%02X", c)
+		} else {
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+// Global spells name as a global symbol reference, e.g. @main or @"π"
+func Global(name string) string {
+	return "@" + Quote(name)
+}
+
+// Local spells name as a local value reference, e.g. %x
+func Local(name string) string {
+	return "%" + Quote(name)
+}
+
+var (
+	ErrEmpty      = errors.New("symbol name is empty")
+	ErrInvalidC   = errors.New("not a valid C identifier")
+	ErrInvalidUTF = errors.New("symbol name is not valid UTF-8")
+	ErrCKeyword   = errors.New("a C or C++ keyword")
+)
+
+// the words a C or C++ compiler reading the generated header won't take as names, the header is wrapped in
+// extern "C" so C++ code can include it too
+var cKeywords = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true, "continue": true, "default": true,
+	"do": true, "double": true, "else": true, "enum": true, "extern": true, "float": true, "for": true,
+	"goto": true, "if": true, "inline": true, "int": true, "long": true, "register": true, "restrict": true,
+	"return": true, "short": true, "signed": true, "sizeof": true, "static": true, "struct": true,
+	"switch": true, "typedef": true, "union": true, "unsigned": true, "void": true, "volatile": true,
+	"while": true, "bool": true, "true": true, "false": true, "_Alignas": true, "_Alignof": true,
+	"_Atomic": true, "_Bool": true, "_Complex": true, "_Generic": true, "_Imaginary": true,
+	"_Noreturn": true, "_Static_assert": true, "_Thread_local": true,
+	"alignas": true, "alignof": true, "asm": true, "catch": true, "class": true, "const_cast": true,
+	"constexpr": true, "decltype": true, "delete": true, "dynamic_cast": true, "explicit": true,
+	"export": true, "friend": true, "mutable": true, "namespace": true, "new": true, "noexcept": true,
+	"nullptr": true, "operator": true, "private": true, "protected": true, "public": true,
+	"reinterpret_cast": true, "static_assert": true, "static_cast": true, "template": true, "this": true,
+	"thread_local": true, "throw": true, "try": true, "typeid": true, "typename": true, "using": true,
+	"virtual": true, "wchar_t": true,
+}
+
+// IsCKeyword reports whether name is reserved in C or C++, so it can't name anything in a header
+func IsCKeyword(name string) bool {
+	return cKeywords[name]
+}
+
+// ValidateExport checks a name given to @export, which must be linkable from C: [A-Za-z_][A-Za-z0-9_]* and not
+// a keyword
+func ValidateExport(name string) error {
+	if name == "" {
+		return ErrEmpty
+	}
+	if !utf8.ValidString(name) {
+		return ErrInvalidUTF
+	}
+	for i, r := range name {
+		if r >= utf8.RuneSelf || !(chars.IsIdentStart(r) || (i > 0 && chars.IsDigit(r))) {
+			return fmt.Errorf("%q: %w", name, ErrInvalidC)
+		}
+	}
+	if IsCKeyword(name) {
+		return fmt.Errorf("%q is %w", name, ErrCKeyword)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package lexer
+
+import "llvm-lang/token"
+
+// A Segment is one piece of a string literal: either plain text, or the source of an interpolated
+// ${...} expression with the delimiters stripped
+type Segment struct {
+	Text string
+	Expr bool
+	Pos  token.Position // of the first byte of Text
+}
+
+// Segments splits the literal of a token.String that l produced into text and ${...} pieces. It reports
+// false when an interpolation is never closed, in which case the segments before it are still returned.
+func (l *Lexer) Segments(tok token.Token) ([]Segment, bool) {
+	s := tok.Literal
+	pos := tok.Pos
+	pos.Offset++ // step past the opening quote
+	pos.Column++
+
+	segments := make([]Segment, 0)
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if !startsInterpolation(s, i) {
+			continue
+		}
+
+		if start < i {
+			segments = append(segments, Segment{Text: s[start:i], Pos: l.advance(pos, s[:start])})
+		}
+
+		end := interpolationEnd(s, i+2)
+		if end == len(s) {
+			return segments, false
+		}
+		segments = append(segments, Segment{Text: s[i+2 : end], Expr: true, Pos: l.advance(pos, s[:i+2])})
+		start = end + 1
+		i = end
+	}
+
+	if start < len(s) {
+		segments = append(segments, Segment{Text: s[start:], Pos: l.advance(pos, s[:start])})
+	}
+	return segments, true
+}
+
+func startsInterpolation(s string, i int) bool {
+	return s[i] == '$' && i+1 < len(s) && s[i+1] == leftCurlyBracket
+}
+
+// index of the quote closing a string whose contents start at i, or len(s) if it runs off the end.
+// Quotes inside ${...} belong to the interpolated expression, not to this string.
+func stringEnd(s string, i int) int {
+	for ; i < len(s); i++ {
+		if s[i] == quote {
+			return i
+		}
+		if startsInterpolation(s, i) {
+			i = interpolationEnd(s, i+2)
+		}
+	}
+	return len(s)
+}
+
+// index of the } closing an interpolation whose body starts at i, or len(s) if it runs off the end
+func interpolationEnd(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case leftCurlyBracket:
+			depth++
+		case rightCurlyBracket:
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case quote:
+			i = stringEnd(s, i+1)
+		}
+	}
+	return len(s)
+}
+
+// the position reached after reading text starting at pos
+func (l *Lexer) advance(pos token.Position, text string) token.Position {
+	for i := 0; i < len(text); i++ {
+		pos.Offset++
+		if text[i] == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else if text[i] == '\t' && l.options.TabWidth > 0 {
+			pos.Column = ((pos.Column-1)/l.options.TabWidth+1)*l.options.TabWidth + 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
@@ -1,8 +1,12 @@
 package lexer
 
 import (
+	"io"
+	"llvm-lang/chars"
+	"llvm-lang/diagnostic"
 	"llvm-lang/token"
-	"llvm-lang/utils"
+	"strings"
+	"unicode/utf8"
 )
 
 type Lexer struct {
@@ -10,6 +14,52 @@ type Lexer struct {
 	position     int
 	readPosition int
 	char         byte
+	line         int // line and column of char
+	column       int
+	filename     string
+	offset       int // added to positions, for sources that are a slice of a larger file
+	options      Options
+	keywords     map[string]token.TokenType
+
+	// for Options.Newlines, the last token other than a comment and the brackets open around the current one
+	last     token.TokenType
+	brackets []byte
+
+	// set when lexing from an io.Reader, source is then only a window onto the input
+	reader  io.Reader
+	readErr error
+
+	diagnostics []diagnostic.Diagnostic
+}
+
+// Diagnostic codes for everything the lexer reports
+const (
+	codeIllegalChar           = "E0001"
+	codeUnterminatedString    = "E0002"
+	codeUnterminatedRawString = "E0003"
+	codeUnterminatedBytes     = "E0004"
+	codeUnterminatedHeredoc   = "E0005"
+	codeHeredocTag            = "E0006"
+	codeHeredocNoTag          = "E0007"
+)
+
+// Options let embedders lex dialects of the language without forking the lexer. The zero value is the
+// standard language.
+type Options struct {
+	// Keywords replaces the keyword table, nil means DefaultKeywords()
+	Keywords map[string]token.TokenType
+	// CaseInsensitiveKeywords makes DEF and Def lex like def, the token literal keeps the original spelling
+	CaseInsensitiveKeywords bool
+	// IdentStart and IdentContinue decide which bytes make up identifiers, nil means ASCII letters, digits and _
+	IdentStart    func(c byte) bool
+	IdentContinue func(c byte) bool
+	// TabWidth moves the column after a tab to the next tab stop, zero counts a tab as one column
+	TabWidth int
+	// Newlines emits a token.Newline at each line break that ends a statement, so semicolons can be left
+	// off. Like Go, a line ends a statement when its last token could end an expression: an identifier,
+	// literal or closing bracket. Unlike Go, line breaks inside ( ) and [ ] never end statements,
+	// and neither does one followed by a line starting with a closing bracket, ., ?, :, && or ||.
+	Newlines bool
 }
 
 const (
@@ -27,6 +77,9 @@ const (
 	colon = ':'
 	dot   = '.'
 	quote = '"'
+	tick  = '`'
+	query = '?'
+	at    = '@'
 
 	plus   = '+'
 	star   = '*'
@@ -39,20 +92,116 @@ const (
 	bang        = '!'
 	ampersand   = '&'
 	pipe        = '|'
+	backslash   = '\\'
 )
 
-var keywords = map[string]token.TokenType{
-	"def":    token.Def,
-	"extern": token.Extern,
+var defaultKeywords = map[string]token.TokenType{
+	"def":     token.Def,
+	"extern":  token.Extern,
+	"struct":  token.Struct,
+	"match":   token.Match,
+	"enum":    token.Enum,
+	"nil":     token.Nil,
+	"in":      token.In,
+	"const":   token.Const,
+	"let":     token.Let,
+	"as":      token.As,
+	"try":     token.Try,
+	"catch":   token.Catch,
+	"true":    token.True,
+	"false":   token.False,
+	"switch":  token.Switch,
+	"case":    token.Case,
+	"default": token.Default,
+	"if":      token.If,
+	"else":    token.Else,
+	"for":     token.For,
+	"while":   token.While,
+	"return":  token.Return,
+	"import":  token.Import,
+
+	"static_assert": token.StaticAssert,
+}
+
+// DefaultKeywords returns a copy of the standard keyword table, a starting point for Options.Keywords
+func DefaultKeywords() map[string]token.TokenType {
+	keywords := make(map[string]token.TokenType, len(defaultKeywords))
+	for word, t := range defaultKeywords {
+		keywords[word] = t
+	}
+	return keywords
 }
 
 func New(source string) *Lexer {
-	lexer := &Lexer{source: source} // Start our lexer at line 1
-	lexer.readChar()                // set up lexer
+	return NewWithOptions(source, Options{})
+}
+
+func NewWithOptions(source string, options Options) *Lexer {
+	lexer := &Lexer{source: source, line: 1} // Start our lexer at line 1
+	lexer.setOptions(options)
+	lexer.readChar() // set up lexer
+	return lexer
+}
+
+// NewFile is New for source read from file, positions of its tokens carry the file's name
+func NewFile(file *token.File, source string) *Lexer {
+	lexer := New(source)
+	if file != nil {
+		lexer.filename = file.Name
+	}
 	return lexer
 }
 
+// NewAt lexes source as if it began at start within a larger file, which is how the expressions inside
+// an interpolated string get positions pointing back into the string
+func NewAt(source string, start token.Position, options Options) *Lexer {
+	lexer := &Lexer{source: source, line: start.Line, column: start.Column - 1, filename: start.Filename, offset: start.Offset}
+	lexer.setOptions(options)
+	lexer.readChar()
+	return lexer
+}
+
+func (l *Lexer) setOptions(options Options) {
+	if options.Keywords == nil {
+		options.Keywords = defaultKeywords
+	}
+	if options.IdentStart == nil {
+		options.IdentStart = isIdentStart
+	}
+	if options.IdentContinue == nil {
+		options.IdentContinue = isIdentContinue
+	}
+	l.options = options
+
+	l.keywords = options.Keywords
+	if options.CaseInsensitiveKeywords {
+		l.keywords = make(map[string]token.TokenType, len(options.Keywords))
+		for word, t := range options.Keywords {
+			l.keywords[strings.ToLower(word)] = t
+		}
+	}
+}
+
+// Options returns the options l was made with, with defaults filled in
+func (l *Lexer) Options() Options {
+	return l.options
+}
+
 func (l *Lexer) readChar() {
+	l.fill(l.readPosition)
+	if l.readPosition > len(l.source) {
+		return // already sitting on EOF
+	}
+
+	if l.char == '\n' {
+		l.line++
+		l.column = 1
+	} else if l.char == '\t' && l.options.TabWidth > 0 {
+		l.column = ((l.column-1)/l.options.TabWidth+1)*l.options.TabWidth + 1
+	} else {
+		l.column++
+	}
+
 	if l.readPosition >= len(l.source) {
 		l.char = 0
 	} else {
@@ -66,39 +215,173 @@ func (l *Lexer) readChar() {
 func (l *Lexer) readIdentifer() string {
 	position := l.position
 
-	for utils.IsAlpha(l.char) {
+	for l.options.IdentContinue(l.char) {
 		l.readChar() // Advances the position pointer
 	}
 	return l.source[position:l.position]
 }
 
+// Reads everything that could belong to a number literal (digits, letters for base prefixes, hex digits
+// and exponents, underscores, dots, and a sign right after an exponent marker). Validating the spelling
+// is left to the parser so that something like 1.2.3 becomes one bad literal instead of several tokens.
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for utils.IsNumeric(l.char) || l.char == dot {
+	hex := l.char == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X')
+
+	// a dot followed by another is a range, as in 0..10, rather than part of the number
+	for isIdentContinue(l.char) || (l.char == dot && l.peekChar() != dot) {
+		exponent := (!hex && (l.char == 'e' || l.char == 'E')) || (hex && (l.char == 'p' || l.char == 'P'))
 		l.readChar() // This just advances the position pointer
+		if exponent && (l.char == plus || l.char == minus) {
+			l.readChar()
+		}
 	}
 	return l.source[position:l.position]
 }
 
+// reads up to the closing quote, skipping over any quotes inside ${...} interpolations
 func (l *Lexer) readString() string {
 	position := l.position + 1 // advance past ""
 
+	l.skipString()
+	return l.source[position:l.position]
+}
+
+// moves from an opening quote to its closing quote, or to EOF
+func (l *Lexer) skipString() {
+	for {
+		l.readChar()
+		switch {
+		case l.char == 0 || l.char == quote:
+			return
+		case l.char == '$' && l.peekChar() == leftCurlyBracket:
+			l.readChar()
+			l.skipInterpolation()
+		}
+	}
+}
+
+// moves from the { of a ${ to the } closing it, or to EOF
+func (l *Lexer) skipInterpolation() {
+	depth := 0
+	for {
+		l.readChar()
+		switch l.char {
+		case 0:
+			return
+		case leftCurlyBracket:
+			depth++
+		case rightCurlyBracket:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case quote:
+			l.skipString()
+		}
+	}
+}
+
+// reads up to the closing backtick, taking everything in between (newlines included) as is
+func (l *Lexer) readRawString() string {
+	position := l.position + 1 // advance past `
+
 	for {
 		l.readChar()
-		if l.char == quote || l.char == 0 {
+		if l.char == tick || l.char == 0 {
 			break
 		}
 	}
 	return l.source[position:l.position]
 }
 
+// reads up to the closing quote of a b"..." literal, skipping over escaped quotes. Escapes are left for the
+// parser to decode.
+func (l *Lexer) readBytes() string {
+	l.readChar() // advance past b
+	position := l.position + 1
+
+	for {
+		l.readChar()
+		if l.char == backslash && l.peekChar() != 0 {
+			l.readChar()
+		} else if l.char == quote || l.char == 0 {
+			break
+		}
+	}
+	return l.source[position:l.position]
+}
+
+// reads a heredoc from its <<< to the end of the line holding just its tag. The body is every line in between,
+// taken as is without the final line break, and the lexer is left on the line break after the tag.
+func (l *Lexer) readHeredoc(pos token.Position) string {
+	l.readChar()
+	l.readChar()
+	l.readChar() // advance past <<<
+	position := l.position
+	for l.options.IdentContinue(l.char) {
+		l.readChar()
+	}
+	tag := l.source[position:l.position]
+	if tag == "" {
+		l.report(pos, codeHeredocNoTag)
+		return "" // a blank line would end it
+	}
+
+	for l.char == ' ' || l.char == '\t' || l.char == '\r' {
+		l.readChar()
+	}
+	if l.char != '\n' && l.char != 0 {
+		l.report(pos, codeHeredocTag, tag)
+		for l.char != '\n' && l.char != 0 {
+			l.readChar()
+		}
+	}
+	if l.char == 0 {
+		l.report(pos, codeUnterminatedHeredoc, tag)
+		return ""
+	}
+
+	body := l.position + 1
+	for l.char != 0 {
+		line := l.position + 1
+		l.readChar()
+		for l.char != '\n' && l.char != 0 {
+			l.readChar()
+		}
+		if strings.TrimSpace(l.source[line:l.position]) == tag {
+			return strings.TrimSuffix(l.source[body:line], "\n")
+		}
+	}
+	l.report(pos, codeUnterminatedHeredoc, tag)
+	return l.source[body:l.position]
+}
+
+// reads from // up to, but not including, the end of the line
+func (l *Lexer) readComment() string {
+	position := l.position
+
+	for l.char != '\n' && l.char != 0 {
+		l.readChar()
+	}
+	return l.source[position:l.position]
+}
+
 func (l *Lexer) skipWhitespace() {
-	for l.char == ' ' || l.char == '\t' || l.char == '\n' || l.char == '\r' {
+	for chars.IsSpace(rune(l.char)) {
+		l.readChar()
+	}
+}
+
+// skipWhitespace without crossing a line break
+func (l *Lexer) skipSpaces() {
+	for l.char != '\n' && chars.IsSpace(rune(l.char)) {
 		l.readChar()
 	}
 }
 
 func (l *Lexer) peekChar() byte {
+	l.fill(l.readPosition)
 	if l.readPosition >= len(l.source) {
 		return 0
 	} else {
@@ -106,16 +389,172 @@ func (l *Lexer) peekChar() byte {
 	}
 }
 
+// the char after peekChar
+func (l *Lexer) peekNextChar() byte {
+	l.fill(l.readPosition + 1)
+	if l.readPosition+1 >= len(l.source) {
+		return 0
+	}
+	return l.source[l.readPosition+1]
+}
+
+// consumes the character starting at the current byte up to its last byte, all of a multibyte UTF-8 character
+// rather than just its lead byte, so it is reported once and as itself. A byte that starts no valid character
+// is consumed alone.
+func (l *Lexer) readIllegal() string {
+	l.fill(l.position + utf8.UTFMax - 1)
+	start := l.position
+	_, size := utf8.DecodeRuneInString(l.source[start:])
+	for i := 1; i < size; i++ {
+		l.readChar()
+	}
+	return l.source[start : l.position+1]
+}
+
+// consumes the current char and the next one as a single token
+func (l *Lexer) makeTwoCharToken(t token.TokenType) token.Token {
+	char := l.char
+	l.readChar() // advance past first char
+	return token.Token{Type: t, Literal: string(char) + string(l.char)}
+}
+
+// the lexer works on bytes, so only ASCII counts here, a UTF-8 lead byte is not a letter
+func isIdentStart(c byte) bool {
+	return c < utf8.RuneSelf && chars.IsIdentStart(rune(c))
+}
+
+func isIdentContinue(c byte) bool {
+	return c < utf8.RuneSelf && chars.IsIdentContinue(rune(c))
+}
+
+func isDigit(c byte) bool {
+	return chars.IsDigit(rune(c))
+}
+
+// LookupIdent classifies ident against the standard keyword table
 func LookupIdent(ident string) token.TokenType {
-	if tok, ok := keywords[ident]; ok {
+	if tok, ok := defaultKeywords[ident]; ok {
+		return tok
+	}
+	return token.Identifier
+}
+
+// IsKeyword reports whether word lexes as a keyword rather than an identifier with l's options
+func (l *Lexer) IsKeyword(word string) bool {
+	return l.lookupIdent(word) != token.Identifier
+}
+
+func (l *Lexer) lookupIdent(ident string) token.TokenType {
+	if l.options.CaseInsensitiveKeywords {
+		ident = strings.ToLower(ident)
+	}
+	if tok, ok := l.keywords[ident]; ok {
 		return tok
 	}
 	return token.Identifier
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	if l.options.Newlines {
+		l.skipSpaces()
+		if l.char == '\n' && l.endsStatement() {
+			l.discard()
+			tok := token.Token{Type: token.Newline, Literal: "\n", Pos: l.pos()}
+			l.readChar()
+			l.last = token.Newline
+			return tok
+		}
+	}
+
 	l.skipWhitespace()
+	l.discard()
+
+	pos := l.pos()
+	tok := l.scanToken(pos)
+	tok.Pos = pos
+
+	if l.options.Newlines {
+		l.track(tok.Type)
+	}
+	return tok
+}
+
+func (l *Lexer) pos() token.Position {
+	return token.Position{Filename: l.filename, Offset: l.offset + l.position, Line: l.line, Column: l.column}
+}
+
+// keeps last and brackets up to date for endsStatement
+func (l *Lexer) track(t token.TokenType) {
+	switch t {
+	case token.Comment:
+		return
+	case token.LeftParen:
+		l.brackets = append(l.brackets, leftParen)
+	case token.LeftSquareBracket:
+		l.brackets = append(l.brackets, leftSquareBracket)
+	case token.LeftCurlyBracket:
+		l.brackets = append(l.brackets, leftCurlyBracket)
+	case token.RightParen, token.RightSquareBracket, token.RightCurlyBracket:
+		if len(l.brackets) > 0 {
+			l.brackets = l.brackets[:len(l.brackets)-1]
+		}
+	}
+	l.last = t
+}
+
+// reports whether the line break at l.char ends a statement, see Options.Newlines
+func (l *Lexer) endsStatement() bool {
+	if len(l.brackets) > 0 && l.brackets[len(l.brackets)-1] != leftCurlyBracket {
+		return false
+	}
+
+	switch l.last {
+	case token.Identifier, token.Number, token.String, token.RawString, token.Bytes, token.Heredoc, token.Nil,
+		token.True, token.False, token.Increment, token.Decrement, token.RightParen, token.RightSquareBracket, token.RightCurlyBracket:
+	default:
+		return false
+	}
+
+	// look past blank lines for a line that carries on the expression
+	i := l.position
+	for {
+		l.fill(i)
+		if i >= len(l.source) || !chars.IsSpace(rune(l.source[i])) {
+			break
+		}
+		i++
+	}
+	if i >= len(l.source) {
+		return true
+	}
+	switch l.source[i] {
+	case rightParen, rightSquareBracket, rightCurlyBracket, dot, query, colon:
+		return false
+	case ampersand, pipe:
+		l.fill(i + 1)
+		return i+1 >= len(l.source) || l.source[i+1] != l.source[i]
+	}
+	return true
+}
+
+// Diagnostics returns the problems found in the input so far, in source order. The tokens involved are still
+// produced, illegal characters as token.Illegal and unterminated strings running to the end of the input.
+func (l *Lexer) Diagnostics() []diagnostic.Diagnostic {
+	return l.diagnostics
+}
+
+// End returns the position just past the last token read, where a missing token after it would go
+func (l *Lexer) End() token.Position {
+	return l.pos()
+}
+
+func (l *Lexer) report(pos token.Position, code string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, diagnostic.New(pos, code, args...))
+}
+
+// scans the token starting at the current char, which sits at pos
+func (l *Lexer) scanToken(pos token.Position) token.Token {
+	var tok token.Token
 	switch l.char {
 	// grouping
 	case leftParen:
@@ -139,30 +578,91 @@ func (l *Lexer) NextToken() token.Token {
 	case colon:
 		tok = token.MakeToken(token.Colon, l.char)
 	case dot:
-		tok = token.MakeToken(token.Dot, l.char)
+		if l.peekChar() == dot && l.peekNextChar() == dot {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.Ellipsis, Literal: "..."}
+		} else if l.peekChar() == dot && l.peekNextChar() == eqSym {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.RangeInclusive, Literal: "..="}
+		} else if l.peekChar() == dot {
+			tok = l.makeTwoCharToken(token.Range)
+		} else {
+			tok = token.MakeToken(token.Dot, l.char)
+		}
+	case query:
+		tok = token.MakeToken(token.Question, l.char)
+	case at:
+		tok = token.MakeToken(token.At, l.char)
 	case quote:
 		tok.Type = token.String
 		tok.Literal = l.readString()
+		if l.char != quote {
+			l.report(pos, codeUnterminatedString)
+		}
+	case tick:
+		tok.Type = token.RawString
+		tok.Literal = l.readRawString()
+		if l.char != tick {
+			l.report(pos, codeUnterminatedRawString)
+		}
 	// Symbols
 	case eqSym:
-		if l.peekChar() == eqSym {
-			char := l.char
-			l.readChar() // advance past first equals
-			literal := string(char) + string(l.char)
-			tok = token.Token{Type: token.EqualTo, Literal: literal}
-		} else {
+		switch l.peekChar() {
+		case eqSym:
+			tok = l.makeTwoCharToken(token.EqualTo)
+		case greaterThan:
+			tok = l.makeTwoCharToken(token.FatArrow)
+		default:
 			tok = token.MakeToken(token.Assign, l.char)
 		}
 	case plus:
-		tok = token.MakeToken(token.Plus, l.char)
+		switch l.peekChar() {
+		case eqSym:
+			tok = l.makeTwoCharToken(token.PlusAssign)
+		case plus:
+			tok = l.makeTwoCharToken(token.Increment)
+		default:
+			tok = token.MakeToken(token.Plus, l.char)
+		}
 	case minus:
-		tok = token.MakeToken(token.Minus, l.char)
+		switch l.peekChar() {
+		case greaterThan:
+			tok = l.makeTwoCharToken(token.Arrow)
+		case eqSym:
+			tok = l.makeTwoCharToken(token.MinusAssign)
+		case minus:
+			tok = l.makeTwoCharToken(token.Decrement)
+		default:
+			tok = token.MakeToken(token.Minus, l.char)
+		}
 	case star:
-		tok = token.MakeToken(token.Star, l.char)
+		switch l.peekChar() {
+		case eqSym:
+			tok = l.makeTwoCharToken(token.StarAssign)
+		case star:
+			tok = l.makeTwoCharToken(token.Power)
+		default:
+			tok = token.MakeToken(token.Star, l.char)
+		}
 	case slash:
-		tok = token.MakeToken(token.Slash, l.char)
+		switch l.peekChar() {
+		case slash:
+			tok.Type = token.Comment
+			tok.Literal = l.readComment()
+			return tok // This is to avoid the l.readChar() call before this functions return
+		case eqSym:
+			tok = l.makeTwoCharToken(token.SlashAssign)
+		default:
+			tok = token.MakeToken(token.Slash, l.char)
+		}
 	case modulo:
-		tok = token.MakeToken(token.Modulo, l.char)
+		if l.peekChar() == eqSym {
+			tok = l.makeTwoCharToken(token.ModuloAssign)
+		} else {
+			tok = token.MakeToken(token.Modulo, l.char)
+		}
 	case greaterThan:
 		if l.peekChar() == eqSym {
 			char := l.char
@@ -173,6 +673,14 @@ func (l *Lexer) NextToken() token.Token {
 			tok = token.MakeToken(token.GreaterThan, l.char)
 		}
 	case lessThan:
+		if l.peekChar() == lessThan && l.peekNextChar() == lessThan {
+			l.fill(l.readPosition + 2)
+			if l.readPosition+2 < len(l.source) && l.options.IdentStart(l.source[l.readPosition+2]) {
+				tok.Type = token.Heredoc
+				tok.Literal = l.readHeredoc(pos)
+				return tok // This is to avoid the l.readChar() call before this functions return, the line break after the tag is a token of its own
+			}
+		}
 		if l.peekChar() == eqSym {
 			char := l.char
 			l.readChar() // advance past first equals
@@ -181,6 +689,8 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = token.MakeToken(token.LessThan, l.char)
 		}
+	case backslash:
+		tok = token.MakeToken(token.Backslash, l.char)
 	case bang:
 		if l.peekChar() == eqSym {
 			char := l.char
@@ -215,20 +725,30 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Type = "EOF"
 
 	default:
-		if utils.IsAlpha(l.char) {
+		if l.char == 'b' && l.peekChar() == quote {
+			tok.Type = token.Bytes
+			tok.Literal = l.readBytes()
+			if l.char != quote {
+				l.report(pos, codeUnterminatedBytes)
+			}
+		} else if l.options.IdentStart(l.char) {
 			tok.Literal = l.readIdentifer()
-			tok.Type = LookupIdent(tok.Literal)
+			tok.Type = l.lookupIdent(tok.Literal)
 			return tok // This is to avoid the l.readChar() call before this functions return
-		} else if utils.IsNumeric(l.char) {
+		} else if isDigit(l.char) {
 			tok.Type = token.Number
 			literal := l.readNumber()
 			tok.Literal = literal
 			return tok // This is to avoid the l.readChar() call before this functions return
 		} else {
-			tok = token.MakeToken(token.Illegal, l.char)
+			tok = token.Token{Type: token.Illegal, Literal: l.readIllegal()}
 		}
 	}
 
+	if tok.Type == token.Illegal {
+		l.report(pos, codeIllegalChar, tok.Literal)
+	}
+
 	l.readChar()
 	return tok
 }
@@ -0,0 +1,62 @@
+package lexer
+
+import (
+	"llvm-lang/token"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// each character the lexer rejects is one Illegal token and one diagnostic, however many bytes it takes
+func TestIllegalCharacter(t *testing.T) {
+	tests := []struct {
+		src     string
+		illegal []string // the literals of the Illegal tokens, in order
+		next    int      // the column of the token after the first Illegal one
+	}{
+		{"let é = 1;", []string{"é"}, 8},
+		{"2 € 3", []string{"€"}, 7},
+		{"x 🙂 y", []string{"🙂"}, 8},
+		{"é€", []string{"é", "€"}, 3},
+		{"\xff\xfe x", []string{"\xff", "\xfe"}, 2},
+		{"\xe2\x82 x", []string{"\xe2", "\x82"}, 2}, // € cut short
+		{"a $ b", []string{"$"}, 5},
+	}
+	for _, test := range tests {
+		tokens, diags := Tokenize(test.src)
+
+		illegal := make([]string, 0)
+		var next token.Token
+		for i, tok := range tokens {
+			if tok.Type == token.Illegal {
+				if len(illegal) == 0 {
+					next = tokens[i+1]
+				}
+				illegal = append(illegal, tok.Literal)
+			}
+		}
+		if len(illegal) != len(test.illegal) || len(diags) != len(test.illegal) {
+			t.Errorf("%q: got illegal tokens %q and diagnostics %v, want %q once each", test.src, illegal, diags, test.illegal)
+			continue
+		}
+		for i := range illegal {
+			if illegal[i] != test.illegal[i] {
+				t.Errorf("%q: illegal token %d is %q, want %q", test.src, i, illegal[i], test.illegal[i])
+			}
+		}
+		if next.Pos.Column != test.next {
+			t.Errorf("%q: %s %q is at column %d, want %d", test.src, next.Type, next.Literal, next.Pos.Column, test.next)
+		}
+	}
+}
+
+// a character split across reads is still read whole
+func TestIllegalCharacterAcrossReads(t *testing.T) {
+	l := NewReader(iotest.OneByteReader(strings.NewReader("é x")), Options{})
+	if tok := l.NextToken(); tok.Type != token.Illegal || tok.Literal != "é" {
+		t.Errorf("got %s %q, want Illegal \"é\"", tok.Type, tok.Literal)
+	}
+	if tok := l.NextToken(); tok.Type != token.Identifier || tok.Literal != "x" {
+		t.Errorf("got %s %q after é, want Identifier \"x\"", tok.Type, tok.Literal)
+	}
+}
@@ -0,0 +1,52 @@
+package lexer
+
+import (
+	"errors"
+	"io"
+)
+
+// how much NewReader pulls from its reader at a time
+const chunkSize = 4096
+
+// NewReader lexes input from r as it is needed, holding only the token being scanned and a chunk of
+// lookahead rather than the whole input. A read error ends the input early, Err reports it.
+func NewReader(r io.Reader, options Options) *Lexer {
+	lexer := &Lexer{line: 1, reader: r}
+	lexer.setOptions(options)
+	lexer.readChar()
+	return lexer
+}
+
+// Err returns the first error other than io.EOF met reading the input of a NewReader lexer
+func (l *Lexer) Err() error {
+	return l.readErr
+}
+
+// makes sure source holds the byte at index i, unless the input runs out first
+func (l *Lexer) fill(i int) {
+	for l.reader != nil && i >= len(l.source) {
+		chunk := make([]byte, chunkSize)
+		n, err := l.reader.Read(chunk)
+		l.source += string(chunk[:n])
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				l.readErr = err
+			}
+			l.reader = nil
+		}
+	}
+}
+
+// drops the input before the current char, called between tokens so no token is mid-scan. Offsets stay
+// relative to the whole input.
+func (l *Lexer) discard() {
+	if l.reader == nil || l.position == 0 {
+		return
+	}
+
+	l.offset += l.position
+	l.source = l.source[l.position:]
+	l.readPosition -= l.position
+	l.position = 0
+}
@@ -0,0 +1,53 @@
+package lexer
+
+import (
+	"llvm-lang/diagnostic"
+	"llvm-lang/token"
+)
+
+// Tokenize lexes all of source up to and including the EOF token. Illegal characters and unterminated
+// strings are reported as diagnostics but their tokens are still kept in the stream.
+func Tokenize(source string) ([]token.Token, []diagnostic.Diagnostic) {
+	tokens := make([]token.Token, 0)
+
+	l := New(source)
+	it := l.Iter()
+	for it.Next() {
+		tokens = append(tokens, it.Token())
+	}
+
+	return tokens, l.Diagnostics()
+}
+
+// Iterator steps through a lexer's tokens in the style of bufio.Scanner:
+//
+//	for it.Next() {
+//		tok := it.Token()
+//	}
+//
+// The EOF token is yielded once, after which Next returns false.
+type Iterator struct {
+	lexer *Lexer
+	tok   token.Token
+	done  bool
+}
+
+func (l *Lexer) Iter() *Iterator {
+	return &Iterator{lexer: l}
+}
+
+func (it *Iterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	it.tok = it.lexer.NextToken()
+	if it.tok.Type == token.EOF {
+		it.done = true
+	}
+	return true
+}
+
+func (it *Iterator) Token() token.Token {
+	return it.tok
+}
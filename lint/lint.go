@@ -0,0 +1,199 @@
+// Package lint reports code that parses fine but is probably not what the author meant. Every finding is a
+// warning with its own code, so it can be silenced with a nolint comment like any other diagnostic.
+package lint
+
+import (
+	"llvm-lang/ast"
+	"llvm-lang/diagnostic"
+	"llvm-lang/index"
+	"strings"
+)
+
+// A Rule is one kind of check, Name is what Config and the vet command refer to it by
+type Rule struct {
+	Name  string
+	Code  string
+	Doc   string
+	check func(*pass)
+}
+
+// Rules lists every rule in the order they run
+var Rules = []*Rule{
+	{Name: "unused-variable", Code: "W0201", Doc: "a variable is assigned but never used", check: unusedVariables},
+	{Name: "unused-parameter", Code: "W0202", Doc: "a parameter is never used, prefix it with _ to say so on purpose", check: unusedParameters},
+	{Name: "shadowed-binding", Code: "W0203", Doc: "a parameter, binding or function hides one of the same name from an enclosing scope", check: shadowedBindings},
+	{Name: "constant-condition", Code: "W0204", Doc: "the condition of ?: involves only constants, so one branch never runs", check: constantConditions},
+	{Name: "discarded-pure-expression", Code: "W0205", Doc: "an expression with no side effects is computed and thrown away", check: discardedPureExprs},
+	{Name: "printf", Code: "W0206", Doc: "a printf or format call whose literal format doesn't match its arguments", check: printfCalls},
+	{Name: "misspelled-name", Code: "W0207", Doc: "a name defined nowhere is a typo or two away from one in scope", check: misspelledNames},
+}
+
+type Config struct {
+	// Disabled holds the names of rules not to run
+	Disabled map[string]bool
+}
+
+// pass is the state a rule sees while checking one program
+type pass struct {
+	program *ast.Program
+	index   *index.Index
+	rule    *Rule
+	diags   []diagnostic.Diagnostic
+}
+
+func (p *pass) report(node *ast.Identifier, args ...interface{}) {
+	diag := diagnostic.Warn(node.Token.Pos, p.rule.Code, args...)
+	diag.Length = len(node.Value)
+	p.diags = append(p.diags, diag)
+}
+
+// report with a note pointing at another identifier
+func (p *pass) reportWithNote(node *ast.Identifier, related *ast.Identifier, note string, args ...interface{}) {
+	p.report(node, args...)
+	p.diags[len(p.diags)-1].Notes = []diagnostic.Note{{Pos: related.Token.Pos, Length: len(related.Value), Message: note}}
+}
+
+// Run checks program with every rule config leaves enabled
+func Run(program *ast.Program, config Config) []diagnostic.Diagnostic {
+	p := &pass{program: program, index: index.Build(program), diags: make([]diagnostic.Diagnostic, 0)}
+
+	for _, rule := range Rules {
+		if config.Disabled[rule.Name] {
+			continue
+		}
+		p.rule = rule
+		rule.check(p)
+	}
+
+	return p.diags
+}
+
+// Lookup finds a rule by name
+func Lookup(name string) (*Rule, bool) {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+func unusedVariables(p *pass) {
+	for _, sym := range p.index.Symbols {
+		if sym.Kind == index.Variable && len(sym.Refs) == 0 && !strings.HasPrefix(sym.Name, "_") {
+			p.report(sym.Def, sym.Name)
+		}
+	}
+}
+
+func unusedParameters(p *pass) {
+	for _, sym := range p.index.Symbols {
+		if sym.Kind == index.Parameter && len(sym.Refs) == 0 && !strings.HasPrefix(sym.Name, "_") {
+			p.report(sym.Def, sym.Name)
+		}
+	}
+}
+
+func shadowedBindings(p *pass) {
+	for _, sym := range p.index.Symbols {
+		if sym.Shadows != nil && !strings.HasPrefix(sym.Name, "_") {
+			p.reportWithNote(sym.Def, sym.Shadows.Def, "the shadowed "+sym.Shadows.Kind.String(), sym.Name, sym.Shadows.Kind, sym.Shadows.Def.Token.Pos)
+		}
+	}
+}
+
+func constantConditions(p *pass) {
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		if cond, ok := node.(*ast.ConditionalExpr); ok && isConstant(cond.Condition) {
+			p.diags = append(p.diags, diagnostic.Warn(cond.Token.Pos, p.rule.Code))
+		}
+		return true
+	})
+}
+
+// only the last statement of a block gives the block its value, a pure statement anywhere before it does
+// nothing. The same goes for the parts of a sequence. Top-level expressions are left alone, they are what a
+// REPL evaluates and prints.
+func discardedPureExprs(p *pass) {
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		if seq, ok := node.(*ast.SequenceExpr); ok {
+			for _, part := range seq.Exprs[:len(seq.Exprs)-1] {
+				if part != nil && isPure(part) {
+					p.diags = append(p.diags, diagnostic.Warn(seq.Token.Pos, p.rule.Code, part.String()))
+				}
+			}
+			return true
+		}
+
+		block, ok := node.(*ast.BlockStmt)
+		if !ok {
+			return true
+		}
+		for i, stmt := range block.Stmts {
+			exprStmt, isExpr := stmt.(*ast.ExpressionStmt)
+			if i == len(block.Stmts)-1 || !isExpr || exprStmt.Expr == nil || !isPure(exprStmt.Expr) {
+				continue
+			}
+			p.diags = append(p.diags, diagnostic.Warn(exprStmt.Token.Pos, p.rule.Code, exprStmt.Expr.String()))
+		}
+		return true
+	})
+}
+
+// reports whether expr is built only from literals and operators on them
+func isConstant(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral, *ast.StringLiteral, *ast.ByteLiteral, *ast.BooleanLiteral, *ast.NilLiteral:
+		return true
+	case *ast.PrefixExpr:
+		return (e.Operator == ast.OpMinus || e.Operator == ast.OpNot) && isConstant(e.Right)
+	case *ast.InfixExpr:
+		return isConstant(e.Left) && isConstant(e.Right)
+	}
+	return false
+}
+
+// reports whether evaluating expr can have no effect beyond producing its value. Calls might do anything,
+// and a named function literal is a definition, so neither is pure.
+func isPure(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Identifier, *ast.NumberLiteral, *ast.StringLiteral, *ast.ByteLiteral, *ast.BooleanLiteral, *ast.NilLiteral:
+		return true
+	case *ast.FunctionLiteral:
+		return e.Name == nil
+	case *ast.InterpolatedString:
+		for _, part := range e.Parts {
+			if !isPure(part) {
+				return false
+			}
+		}
+		return true
+	case *ast.PrefixExpr:
+		return e.Operator != ast.OpIncrement && e.Operator != ast.OpDecrement && isPure(e.Right)
+	case *ast.InfixExpr:
+		return isPure(e.Left) && isPure(e.Right)
+	case *ast.ConditionalExpr:
+		return isPure(e.Condition) && isPure(e.Consequence) && isPure(e.Alternative)
+	case *ast.SequenceExpr:
+		for _, part := range e.Exprs {
+			if !isPure(part) {
+				return false
+			}
+		}
+		return true
+	case *ast.CastExpr:
+		return isPure(e.Value)
+	case *ast.RangeExpr:
+		return isPure(e.Start) && isPure(e.End)
+	case *ast.FieldAccessExpr:
+		return isPure(e.Object)
+	case *ast.StructLiteral:
+		for _, field := range e.Fields {
+			if !isPure(field.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
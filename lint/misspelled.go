@@ -0,0 +1,80 @@
+package lint
+
+import (
+	"llvm-lang/diagnostic"
+	"llvm-lang/index"
+)
+
+// reports unresolved names close enough to a symbol in scope to be a typo of it, with a fix renaming them to
+// it. Names with nothing close are left alone, they are most likely builtins.
+func misspelledNames(p *pass) {
+	for _, ident := range p.index.Unresolved {
+		sym, ok := closest(ident.Value, p.index.Visible(ident))
+		if !ok {
+			continue
+		}
+
+		diag := diagnostic.Warn(ident.Token.Pos, p.rule.Code, ident.Value, sym.Name)
+		diag.Length = len(ident.Value)
+		diag.Notes = []diagnostic.Note{{Pos: sym.Def.Token.Pos, Length: len(sym.Name), Message: sym.Name + " is declared here"}}
+		diag.Fixes = []diagnostic.Fix{{
+			Message: "replace with " + sym.Name,
+			Edits:   []diagnostic.Edit{{Pos: ident.Token.Pos, Length: len(ident.Value), NewText: sym.Name}},
+		}}
+		p.diags = append(p.diags, diag)
+	}
+}
+
+// the symbol whose name is fewest edits from name, within a budget that grows with its length so short names
+// don't match everything. Ties go to the symbol defined first.
+func closest(name string, candidates []*index.Symbol) (*index.Symbol, bool) {
+	budget := 1
+	if len([]rune(name)) > 5 {
+		budget = 2
+	}
+
+	var best *index.Symbol
+	for _, sym := range candidates {
+		if d := distance(name, sym.Name); d <= budget {
+			best, budget = sym, d-1
+		}
+	}
+	return best, best != nil
+}
+
+// the edit distance between a and b in runes, counting a swap of neighbouring runes as one edit like an
+// insertion, deletion or substitution, since transposed letters are the commonest typo
+func distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = minOf(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = minOf(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+func minOf(first int, rest ...int) int {
+	for _, n := range rest {
+		if n < first {
+			first = n
+		}
+	}
+	return first
+}
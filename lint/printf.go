@@ -0,0 +1,86 @@
+package lint
+
+import (
+	"fmt"
+	"llvm-lang/ast"
+	"llvm-lang/diagnostic"
+)
+
+// the builtins whose first argument is a format string
+var printfFuncs = map[string]bool{
+	"printf": true,
+	"format": true,
+}
+
+// checks calls to printf and format whose format is a plain string literal: every verb must be one of %d,
+// %f, %s and %%, there must be exactly one argument per verb, and a literal argument must suit its verb.
+// Arguments that aren't literals need types to check and are let through.
+func printfCalls(p *pass) {
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Arguments) == 0 {
+			return true
+		}
+		fn, ok := call.Function.(*ast.Identifier)
+		if !ok || !printfFuncs[fn.Value] {
+			return true
+		}
+		if _, userDefined := p.index.Lookup(fn); userDefined {
+			return true
+		}
+		format, ok := call.Arguments[0].(*ast.StringLiteral)
+		if !ok {
+			return true
+		}
+
+		report := func(detail string) {
+			p.diags = append(p.diags, diagnostic.Warn(call.Token.Pos, p.rule.Code, fn.Value, detail))
+		}
+
+		args := call.Arguments[1:]
+		verbs := 0
+		for i := 0; i < len(format.Value); i++ {
+			if format.Value[i] != '%' {
+				continue
+			}
+			i++
+			if i == len(format.Value) {
+				report("format ends with a lone %")
+				return true
+			}
+
+			verb := format.Value[i]
+			switch verb {
+			case '%':
+				continue
+			case 'd', 'f', 's':
+			default:
+				report(fmt.Sprintf("unknown verb %%%c", verb))
+				return true
+			}
+
+			if verbs < len(args) && !suits(verb, args[verbs]) {
+				report(fmt.Sprintf("%%%c does not take %s", verb, args[verbs].String()))
+			}
+			verbs++
+		}
+
+		if verbs != len(args) {
+			report(fmt.Sprintf("format has %d verbs but the call passes %d arguments", verbs, len(args)))
+		}
+		return true
+	})
+}
+
+// reports whether arg can be printed with verb, as far as can be told without types
+func suits(verb byte, arg ast.Expr) bool {
+	switch arg.(type) {
+	case *ast.NumberLiteral:
+		return verb == 'd' || verb == 'f'
+	case *ast.StringLiteral, *ast.InterpolatedString:
+		return verb == 's'
+	case *ast.NilLiteral:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"llvm-lang/ast"
+	"llvm-lang/callgraph"
+	"llvm-lang/cheader"
+	"llvm-lang/diagnostic"
+	"llvm-lang/driver"
+	"llvm-lang/lexer"
+	"llvm-lang/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// how many errors are shown before giving up, unless --max-errors says otherwise
+const defaultMaxErrors = 20
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rename":
+			os.Exit(runRename(os.Args[2:]))
+		case "vet":
+			os.Exit(runVet(os.Args[2:]))
+		case "doc":
+			os.Exit(runDoc(os.Args[2:]))
+		case "version":
+			os.Exit(runVersion(os.Args[2:]))
+		case "toolchain":
+			os.Exit(runToolchain(os.Args[2:]))
+		}
+	}
+
+	emit := flag.String("emit", "", "what to print: tokens, ast, ast-dot, callgraph-dot, callgraph-json, c-header")
+	format := flag.String("format", "text", "output format for --emit=tokens: text or json")
+	showSuppressed := flag.Bool("show-suppressed", false, "also list diagnostics silenced by nolint comments")
+	lang := flag.String("lang", diagnostic.DefaultLocale, "language for diagnostic messages")
+	diagFormat := flag.String("diag-format", diagnostic.FormatText, "how to print diagnostics: text, json or sarif")
+	maxErrors := flag.Int("max-errors", defaultMaxErrors, "stop after this many errors, 0 for no limit")
+	color := flag.String("color", "auto", "color text diagnostics: auto (when stderr is a terminal), always or never")
+	fix := flag.Bool("fix", false, "make the suggested fixes, rewriting the file, or printing the result when reading stdin")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: llvm-lang [flags] [file]\n       llvm-lang rename [-w] file line:column newName\n       llvm-lang vet [-disable rules] [-list] [-diag-format format] [-fix] [file]\n       llvm-lang doc [file]\n       llvm-lang version [-json]\n       llvm-lang toolchain [-clang path] [-lld path] [-llc path] [-json]\n\nReads from stdin when no file is given.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if !checkDiagFormat(*diagFormat) {
+		os.Exit(2)
+	}
+	switch *color {
+	case "auto", "always", "never":
+		colorMode = *color
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --color %q, want auto, always or never\n", *color)
+		os.Exit(2)
+	}
+
+	source, err := readSource(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *emit == "tokens" {
+		os.Exit(emitTokens(source, *format, *lang, *diagFormat))
+	}
+
+	name := flag.Arg(0)
+	if name == "" || name == "-" {
+		name = "<stdin>"
+	}
+	result := driver.Check([]byte(source), driver.Options{Filename: name, MaxErrors: *maxErrors})
+	if *fix {
+		unfixed, err := applyFixes(flag.Arg(0), source, result.Diagnostics)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if report(source, unfixed, result.Suppressed, *showSuppressed, *lang, *diagFormat) {
+			os.Exit(1)
+		}
+		return
+	}
+	if hasErrors := report(source, result.Diagnostics, result.Suppressed, *showSuppressed, *lang, *diagFormat); hasErrors {
+		os.Exit(1)
+	}
+	program := result.Program
+
+	switch *emit {
+	case "":
+	case "ast":
+		fmt.Print(ast.Dump(program))
+	case "ast-dot":
+		fmt.Print(ast.Dot(program))
+	case "callgraph-dot":
+		fmt.Print(callgraph.Build(program).Dot())
+	case "callgraph-json":
+		out, err := callgraph.Build(program).JSON()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+	case "c-header":
+		header, err := cheader.Generate(program, headerGuard(flag.Arg(0)))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(header)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --emit mode %q\n", *emit)
+		os.Exit(2)
+	}
+}
+
+// prints diagnostics in order to stderr in format and reports whether any of them is an error. Text diagnostics
+// quote the lines of source they are on.
+func report(source string, diags []diagnostic.Diagnostic, suppressed []diagnostic.Diagnostic, showSuppressed bool, lang string, format string) bool {
+	if !showSuppressed {
+		suppressed = nil
+	}
+
+	var err error
+	if format == diagnostic.FormatText {
+		options := diagnostic.PrettyOptions{
+			Color:  useColor(os.Stderr),
+			Source: func(string) []byte { return []byte(source) }, // every diagnostic is in the one file being checked
+		}
+		err = diagnostic.WritePretty(os.Stderr, localize(diags, lang), localize(suppressed, lang), options)
+	} else {
+		err = diagnostic.Write(os.Stderr, format, localize(diags, lang), localize(suppressed, lang))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	for _, diag := range diags {
+		if diag.Severity == diagnostic.Error {
+			return true
+		}
+	}
+	return false
+}
+
+// an include guard from the source file's name, like LIB_H for lib.ll
+func headerGuard(path string) string {
+	base := filepath.Base(path)
+	if path == "" || path == "-" {
+		base = "stdin"
+	}
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	guard := []byte(strings.ToUpper(base) + "_H")
+	for i, c := range guard {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' && i > 0) {
+			guard[i] = '_'
+		}
+	}
+	return string(guard)
+}
+
+// sorted copies of diags with their messages in lang
+func localize(diags []diagnostic.Diagnostic, lang string) []diagnostic.Diagnostic {
+	out := make([]diagnostic.Diagnostic, 0, len(diags))
+	for _, diag := range diags {
+		out = append(out, diag.Localize(lang))
+	}
+	diagnostic.Sort(out)
+	return diagnostic.Unique(out)
+}
+
+func checkDiagFormat(format string) bool {
+	switch format {
+	case diagnostic.FormatText, diagnostic.FormatJSON, diagnostic.FormatSARIF:
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "unknown --diag-format %q, want text, json or sarif\n", format)
+	return false
+}
+
+func emitTokens(source string, format string, lang string, diagFormat string) int {
+	tokens, diags := lexer.Tokenize(source)
+
+	switch format {
+	case "text":
+		for _, tok := range tokens {
+			fmt.Printf("%s\t%s\t%q\n", tok.Pos, tok.Type, tok.Literal)
+		}
+	case "json":
+		type jsonToken struct {
+			Type    token.TokenType `json:"type"`
+			Literal string          `json:"literal"`
+			Line    int             `json:"line"`
+			Column  int             `json:"column"`
+		}
+		out := make([]jsonToken, 0, len(tokens))
+		for _, tok := range tokens {
+			out = append(out, jsonToken{Type: tok.Type, Literal: tok.Literal, Line: tok.Pos.Line, Column: tok.Pos.Column})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(out); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --format %q\n", format)
+		return 2
+	}
+
+	report(source, diags, nil, false, lang, diagFormat)
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func readSource(path string) (string, error) {
+	if path == "" || path == "-" {
+		source, err := io.ReadAll(os.Stdin)
+		return string(source), err
+	}
+
+	source, err := os.ReadFile(path)
+	return string(source), err
+}
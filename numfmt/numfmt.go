@@ -0,0 +1,89 @@
+// Package numfmt is the one place that decides how numbers print, so a folded constant, a literal echoed back
+// by the formatter and a number printed by a running program all spell the same value the same way.
+//
+// Whole numbers print without a decimal point. Anything else prints as the shortest decimal that reads back as
+// the same float64. Exponent form is kept for magnitudes from 1e21 up and below 1e-6, written like 1.5e21 and
+// 2e-7, without a + or leading zeros in the exponent. NaN and the infinities print as nan, inf and -inf.
+package numfmt
+
+import (
+	"go/constant"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// the decimal exponents past which numbers print in exponent form
+const (
+	minPlainExponent = -6
+	maxPlainExponent = 20
+)
+
+// Float formats f by the rules above
+func Float(f float64) string {
+	return Precision(f, -1)
+}
+
+// Precision is Float rounded to at most digits significant digits, -1 means as many as round-tripping takes.
+// Trailing zeros are dropped either way, so Precision(2.5, 3) is 2.5 rather than 2.50.
+func Precision(f float64, digits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	if digits == 0 {
+		digits = 1
+	}
+	if digits > 0 {
+		digits-- // 'e' counts the digits after the point
+	}
+
+	// d.ddddde±XX, split into its sign, significant digits and exponent
+	s := strconv.FormatFloat(f, 'e', digits, 64)
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	e := strings.IndexByte(s, 'e')
+	exponent, _ := strconv.Atoi(s[e+1:])
+	significand := strings.TrimRight(strings.Replace(s[:e], ".", "", 1), "0")
+	if significand == "" {
+		return sign + "0"
+	}
+
+	switch {
+	case exponent < minPlainExponent || exponent > maxPlainExponent:
+		out := sign + significand[:1]
+		if len(significand) > 1 {
+			out += "." + significand[1:]
+		}
+		return out + "e" + strconv.Itoa(exponent)
+	case exponent < 0:
+		return sign + "0." + strings.Repeat("0", -exponent-1) + significand
+	case len(significand) <= exponent+1:
+		return sign + significand + strings.Repeat("0", exponent+1-len(significand))
+	default:
+		return sign + significand[:exponent+1] + "." + significand[exponent+1:]
+	}
+}
+
+// Constant formats a numeric constant. Whole numbers below 1e21 print exactly, digit for digit, everything
+// else goes through Float.
+func Constant(value constant.Value) string {
+	if value == nil || value.Kind() == constant.Unknown {
+		return "?"
+	}
+
+	if exact := constant.ToInt(value); exact.Kind() == constant.Int {
+		if f, _ := constant.Float64Val(exact); math.Abs(f) < 1e21 {
+			return exact.ExactString()
+		}
+	}
+
+	f, _ := constant.Float64Val(value)
+	return Float(f)
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"llvm-lang/ast"
+	"llvm-lang/token"
+)
+
+// The methods here let embedders add syntax of their own without patching the parser. New keywords come from
+// lexer.Options.Keywords, and the parse functions registered for them drive the parser with the cursor
+// methods below, building their results from the ast package.
+//
+//	p.RegisterStatement(keyword, func() ast.Stmt {
+//		stmt := &ast.ExpressionStmt{Token: p.CurrToken()}
+//		p.NextToken() // advance past the keyword
+//		stmt.Expr = p.ParseExpression(parser.LOWEST)
+//		if p.PeekToken().Type == token.Semicolon {
+//			p.NextToken()
+//		}
+//		return stmt
+//	})
+//
+// Registering a token that already has a parse function replaces it.
+
+// RegisterPrefix makes fn parse expressions that start with tokenType
+func (p *Parser) RegisterPrefix(tokenType token.TokenType, fn PrefixParseFn) {
+	p.registerPrefix(tokenType, fn)
+}
+
+// RegisterInfix makes fn parse tokenType as a binary operator binding with precedence, one of the tiers
+// documented on Precedence
+func (p *Parser) RegisterInfix(tokenType token.TokenType, precedence Precedence, fn InfixParseFn) {
+	p.precedences[tokenType] = precedence
+	p.registerInfix(tokenType, fn)
+}
+
+// RegisterPostfix makes fn parse tokenType following an expression as a postfix operator, like the ! of a
+// factorial. Postfix operators bind tighter than prefix and binary ones, and looser than calls and field
+// access, so -x! is -(x!) and f(x)! is (f(x))!. A token registered as postfix is no longer taken as infix.
+func (p *Parser) RegisterPostfix(tokenType token.TokenType, fn PostfixParseFn) {
+	p.registerPostfix(tokenType, fn)
+}
+
+// RegisterAssociativity sets how a run of tokenType operators groups, operators are left associative unless
+// registered otherwise. Infix parse functions honour it by parsing their right operand with ParseOperand.
+func (p *Parser) RegisterAssociativity(tokenType token.TokenType, associativity Associativity) {
+	p.associativities[tokenType] = associativity
+}
+
+// RegisterStatement makes fn parse statements that start with tokenType, usually a keyword
+func (p *Parser) RegisterStatement(tokenType token.TokenType, fn StatementParseFn) {
+	p.registerStatement(tokenType, fn)
+}
+
+func (p *Parser) CurrToken() token.Token {
+	return p.currToken
+}
+
+func (p *Parser) PeekToken() token.Token {
+	return p.peekToken
+}
+
+// NextToken advances to the next token
+func (p *Parser) NextToken() {
+	p.nextToken()
+}
+
+// ExpectPeek advances if the next token has type t, and reports an error otherwise
+func (p *Parser) ExpectPeek(t token.TokenType) bool {
+	return p.expectPeek(t)
+}
+
+// ParseExpression parses an expression starting at the current token, stopping before any operator that
+// binds no tighter than precedence
+func (p *Parser) ParseExpression(precedence Precedence) ast.Expr {
+	return p.parseExpression(precedence)
+}
+
+// ParseOperand parses the right operand of the operator that is the current token, after advancing past it,
+// grouping by the operator's precedence and associativity
+func (p *Parser) ParseOperand() ast.Expr {
+	operand := p.operandPrecedence()
+	p.nextToken()
+	return p.parseExpression(operand)
+}
+
+// ParseBlock parses a { ... } block, the current token must be its {
+func (p *Parser) ParseBlock() *ast.BlockStmt {
+	return p.parseBlockStmt()
+}
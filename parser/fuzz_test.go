@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"llvm-lang/ast"
+	"llvm-lang/lexer"
+	"testing"
+)
+
+// inputs covering every construct, and a few broken ones, for the fuzzer to mutate
+var fuzzSeeds = []string{
+	"",
+	"let x = 1 + 2 * 3;",
+	"let x: int = -(4 ** 2) % 3;",
+	"x++; p.y--; ++x;",
+	"const C = 1.5e3; static_assert(C > 1, \"big\");",
+	"def add(a: int, b: int): int { a + b }",
+	"def id<T>(x: T): T { x }",
+	"pure def f(xs...) { xs }",
+	"@inline @export(\"c_add\") def add(a, b) { a + b }",
+	"struct P { x: float; y: float } let p = P { x: 1, y: 2 }; p.x",
+	"enum Shape { Circle(float), Square(float) }",
+	"match s { Shape.Circle(r) => r, _ => 0 }",
+	"for i in 0..10 { i += 1; }",
+	"for i in 0..=10 { }",
+	"switch (x) { case 1, 2: a; case \"s\": b; default: c; }",
+	"try { f() } catch (e) { e } try { g() } catch { 0 }",
+	"let f = \\x -> x * 2; f(3)",
+	"a == b ? c : d as float",
+	"\"x is ${x + 1} and ${\"nested ${y}\"}\"",
+	"`raw\nstring`",
+	"let b = b\"\\x00\\x01\";",
+	"let h = <<<EOF\nline\nEOF\n",
+	"let s = (f(); g(); 3);",
+	"test \"adds\" { static_assert(1 + 1 == 2); }",
+	"/// doc\ndef documented() { }",
+	"let x = (1 + 2;",
+	"def f() { 1;",
+	"<<<A",
+	"b\"\\q",
+	"\"${",
+	"((((((((((",
+	"match { => }",
+	"@",
+	"let é = 1;",
+}
+
+// the parser must turn any input into a program and diagnostics, never a panic
+func FuzzParseProgram(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		for _, options := range []lexer.Options{{}, {Newlines: true}} {
+			p := New(lexer.NewWithOptions(src, options))
+			program := p.ParseProgram()
+			_ = program.String()
+			_ = ast.Dump(program)
+			_ = p.Diagnostics()
+		}
+	})
+}
@@ -2,116 +2,413 @@ package parser
 
 import (
 	"fmt"
+	"go/constant"
+	gotoken "go/token"
+	"io"
 	"llvm-lang/ast"
+	"llvm-lang/diagnostic"
 	"llvm-lang/lexer"
 	"llvm-lang/token"
+	"os"
 	"strconv"
+	"strings"
 )
 
 type (
-	prefixParseFn func() ast.Expr
-	infixParseFn  func(ast.Expr) ast.Expr
+	// A PrefixParseFn parses an expression starting at the current token. It leaves the current token on the
+	// last token of the expression rather than past it.
+	PrefixParseFn func() ast.Expr
+
+	// An InfixParseFn parses the rest of an expression whose left operand is given, starting with the current
+	// token on the operator. Like a PrefixParseFn it stops on the expression's last token.
+	InfixParseFn func(ast.Expr) ast.Expr
+
+	// A PostfixParseFn finishes an expression whose operand is given, with the current token on the postfix
+	// operator. Usually the operator is the expression's last token and the function only builds the node.
+	PostfixParseFn func(ast.Expr) ast.Expr
+
+	// A StatementParseFn parses a statement starting with the keyword that is the current token, including the
+	// semicolon ending it if there is one
+	StatementParseFn func() ast.Stmt
 )
 
 type Precedence int
 
+// Binding power of each operator tier, loosest first
+//
+//	ASSIGN      = += -= *= /= %=   right associative
+//	TERNARY     ?:                 right associative
+//	RANGE       .. ..=             non-associative, `0..n+1` is `0..(n+1)`
+//	OR          ||
+//	AND         &&
+//	EQUALS      == !=
+//	COMPARISON  < > <= >= in       non-associative, `a < b < c` is an error
+//	SUM         + -
+//	PRODUCT     * / %
+//	CAST        x as T             binds tighter than arithmetic but looser than prefix, so -x as int is (-x) as int
+//	PREFIX      -x !x ++x --x
+//	POWER       **                 right associative, binds tighter than prefix so -2 ** 2 is -(2 ** 2)
+//	POSTFIX     x++ x--            see RegisterPostfix for more
+//	CALL        f(x) x.y T { }
+//	INDEX       x[i]
+//
+// everything not marked otherwise is left associative, see associativities
 const (
 	LOWEST Precedence = iota + 1
-	ANDOR             // I think this is right
+	ASSIGN
+	TERNARY
+	RANGE
+	OR
+	AND
 	EQUALS
-	LESSGREATEREQUAL
-	LESSGREATER
+	COMPARISON
 	SUM
 	PRODUCT
+	CAST
 	PREFIX
+	POWER
+	POSTFIX
 	CALL
 	INDEX
 )
 
+// the binding powers every parser starts with, RegisterInfix adds to a parser's own copy
 var precedences = map[token.TokenType]Precedence{
-	token.And:                ANDOR,
-	token.Or:                 ANDOR,
+	token.Assign:             ASSIGN,
+	token.PlusAssign:         ASSIGN,
+	token.MinusAssign:        ASSIGN,
+	token.StarAssign:         ASSIGN,
+	token.SlashAssign:        ASSIGN,
+	token.ModuloAssign:       ASSIGN,
+	token.Question:           TERNARY,
+	token.Range:              RANGE,
+	token.RangeInclusive:     RANGE,
+	token.Or:                 OR,
+	token.And:                AND,
 	token.EqualTo:            EQUALS,
 	token.NotEqualTo:         EQUALS,
-	token.LessThan:           LESSGREATER,
-	token.GreaterThan:        LESSGREATER,
-	token.GreaterThanEqualTo: LESSGREATEREQUAL,
-	token.LessThanEqualTo:    LESSGREATEREQUAL,
+	token.LessThan:           COMPARISON,
+	token.GreaterThan:        COMPARISON,
+	token.GreaterThanEqualTo: COMPARISON,
+	token.LessThanEqualTo:    COMPARISON,
+	token.In:                 COMPARISON,
 	token.Plus:               SUM,
 	token.Minus:              SUM,
 	token.Slash:              PRODUCT,
 	token.Star:               PRODUCT,
 	token.Modulo:             PRODUCT,
+	token.As:                 CAST,
+	token.Power:              POWER,
 	token.LeftParen:          CALL,
+	token.LeftCurlyBracket:   CALL,
+	token.Dot:                CALL,
 	token.LeftSquareBracket:  INDEX,
 }
 
+// Associativity says how a run of operators with the same precedence groups
+type Associativity int
+
+const (
+	LeftAssociative  Associativity = iota // a - b - c is (a - b) - c
+	RightAssociative                      // a = b = c is a = (b = c)
+	NonAssociative                        // a < b < c is an error
+)
+
+// the operators that aren't left associative, RegisterAssociativity adds to a parser's own copy
+var associativities = map[token.TokenType]Associativity{
+	token.Assign:             RightAssociative,
+	token.PlusAssign:         RightAssociative,
+	token.MinusAssign:        RightAssociative,
+	token.StarAssign:         RightAssociative,
+	token.SlashAssign:        RightAssociative,
+	token.ModuloAssign:       RightAssociative,
+	token.Question:           RightAssociative,
+	token.Power:              RightAssociative,
+	token.LessThan:           NonAssociative,
+	token.GreaterThan:        NonAssociative,
+	token.GreaterThanEqualTo: NonAssociative,
+	token.LessThanEqualTo:    NonAssociative,
+	token.In:                 NonAssociative,
+	token.Range:              NonAssociative,
+	token.RangeInclusive:     NonAssociative,
+}
+
+// Diagnostic codes for everything the parser reports
+const (
+	codeInternal          = "E0100"
+	codeNoPrefix          = "E0101"
+	codeUnexpectedToken   = "E0102"
+	codeBadNumber         = "E0103"
+	codeChainedComparison = "E0104"
+	codeBadAssignTarget   = "E0105"
+	codeBadStructLiteral  = "E0106"
+	codeTooDeep           = "E0107"
+	codeBadPattern        = "E0108"
+	codeBadInterpolation  = "E0109"
+	codeBadStaticAssert   = "E0110"
+	codeReservedWord      = "E0111"
+	codeBadPure           = "E0112"
+	codeBadAttributed     = "E0113"
+	codeBadEscape         = "E0114"
+)
+
+// DefaultMaxDepth is how deeply expressions may nest when Options.MaxDepth is left at zero
+const DefaultMaxDepth = 1000
+
+type Options struct {
+	// MaxDepth caps expression nesting, so inputs like ((((...)))) or ------x report an error
+	// instead of exhausting the stack
+	MaxDepth int
+
+	// MaxErrors stops parsing once that many errors have been found, with one more saying so. Zero means
+	// there is no limit.
+	MaxErrors int
+
+	// Trace, when set, receives an indented log of every parse function entered and left, see TraceEnv
+	Trace io.Writer
+}
+
 type Parser struct {
-	lexer *lexer.Lexer
+	lexer   *lexer.Lexer
+	options Options
+
+	depth      int
+	abandoned  bool // set once MaxDepth or MaxErrors is hit, the rest of the input is skipped
+	traceLevel int
+
+	// while parsing the head of a construct followed by a block, like `match x { ... }`, a { belongs to
+	// the construct rather than starting a struct literal
+	noStructLiteral bool
 
 	currToken token.Token
 	peekToken token.Token
+	currEnd   token.Position // just past currToken, where fixes insert what is missing after it
+	peekEnd   token.Position
 
-	errors []string
+	diagnostics []diagnostic.Diagnostic
+	comments    []*ast.Comment
+	lexed       int // how many of the lexer's diagnostics have been taken into diagnostics
 
-	prefixParseFns map[token.TokenType]prefixParseFn
-	infixParseFns  map[token.TokenType]infixParseFn
+	prefixParseFns    map[token.TokenType]PrefixParseFn
+	infixParseFns     map[token.TokenType]InfixParseFn
+	postfixParseFns   map[token.TokenType]PostfixParseFn
+	statementParseFns map[token.TokenType]StatementParseFn
+	precedences       map[token.TokenType]Precedence
+	associativities   map[token.TokenType]Associativity
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{lexer: l, errors: make([]string, 0)}
+	return NewWithOptions(l, Options{})
+}
+
+func NewWithOptions(l *lexer.Lexer, options Options) *Parser {
+	if options.MaxDepth <= 0 {
+		options.MaxDepth = DefaultMaxDepth
+	}
+	if options.Trace == nil && os.Getenv(TraceEnv) != "" {
+		options.Trace = os.Stderr
+	}
+
+	p := &Parser{lexer: l, options: options, diagnostics: make([]diagnostic.Diagnostic, 0), comments: make([]*ast.Comment, 0)}
+
+	p.precedences = make(map[token.TokenType]Precedence, len(precedences))
+	for tokenType, precedence := range precedences {
+		p.precedences[tokenType] = precedence
+	}
+	p.associativities = make(map[token.TokenType]Associativity, len(associativities))
+	for tokenType, associativity := range associativities {
+		p.associativities[tokenType] = associativity
+	}
 
 	// peekToken and currToken are initialized to the zero value of token.Token, so we advance twice
 	p.nextToken() // set peek
 	p.nextToken() // set curr and peek
 
-	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.prefixParseFns = make(map[token.TokenType]PrefixParseFn)
 
 	p.registerPrefix(token.Identifier, p.parseIdentifier)
 	p.registerPrefix(token.Number, p.parseNumberLiteral)
+	p.registerPrefix(token.String, p.parseStringLiteral)
+	p.registerPrefix(token.RawString, p.parseRawStringLiteral)
+	p.registerPrefix(token.Bytes, p.parseByteLiteral)
+	p.registerPrefix(token.Heredoc, p.parseHeredoc)
+	p.registerPrefix(token.Nil, p.parseNilLiteral)
+	p.registerPrefix(token.True, p.parseBooleanLiteral)
+	p.registerPrefix(token.False, p.parseBooleanLiteral)
 	p.registerPrefix(token.Bang, p.parsePrefixExpr)
 	p.registerPrefix(token.Minus, p.parsePrefixExpr)
+	p.registerPrefix(token.Increment, p.parseUpdateExpr)
+	p.registerPrefix(token.Decrement, p.parseUpdateExpr)
 	p.registerPrefix(token.LeftParen, p.parseGroupedExpr)
+	p.registerPrefix(token.Def, p.parseFunctionLiteral)
+	p.registerPrefix(token.Backslash, p.parseLambda)
+	p.registerPrefix(token.Match, p.parseMatchExpr)
+	p.registerPrefix(token.Try, p.parseTryExpr)
 
-	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	p.infixParseFns = make(map[token.TokenType]InfixParseFn)
 	p.registerInfix(token.Plus, p.parseInfixExpr)
 	p.registerInfix(token.Minus, p.parseInfixExpr)
 	p.registerInfix(token.Slash, p.parseInfixExpr)
 	p.registerInfix(token.Star, p.parseInfixExpr)
 	p.registerInfix(token.Modulo, p.parseInfixExpr)
+	p.registerInfix(token.Power, p.parseInfixExpr)
 	p.registerInfix(token.EqualTo, p.parseInfixExpr)
 	p.registerInfix(token.NotEqualTo, p.parseInfixExpr)
 	p.registerInfix(token.GreaterThanEqualTo, p.parseInfixExpr)
 	p.registerInfix(token.LessThanEqualTo, p.parseInfixExpr)
 	p.registerInfix(token.GreaterThan, p.parseInfixExpr)
 	p.registerInfix(token.LessThan, p.parseInfixExpr)
+	p.registerInfix(token.In, p.parseInfixExpr)
 	p.registerInfix(token.And, p.parseInfixExpr)
 	p.registerInfix(token.Or, p.parseInfixExpr)
+	p.registerInfix(token.Assign, p.parseAssignExpr)
+	p.registerInfix(token.PlusAssign, p.parseAssignExpr)
+	p.registerInfix(token.MinusAssign, p.parseAssignExpr)
+	p.registerInfix(token.StarAssign, p.parseAssignExpr)
+	p.registerInfix(token.SlashAssign, p.parseAssignExpr)
+	p.registerInfix(token.ModuloAssign, p.parseAssignExpr)
+	p.registerInfix(token.Question, p.parseConditionalExpr)
+	p.registerInfix(token.As, p.parseCastExpr)
+	p.registerInfix(token.Range, p.parseRangeExpr)
+	p.registerInfix(token.RangeInclusive, p.parseRangeExpr)
 	p.registerInfix(token.LeftParen, p.parseCallExpr)
+	p.registerInfix(token.LeftCurlyBracket, p.parseStructLiteral)
+	p.registerInfix(token.Dot, p.parseFieldAccessExpr)
+
+	p.postfixParseFns = make(map[token.TokenType]PostfixParseFn)
+	p.registerPostfix(token.Increment, p.parsePostfixUpdateExpr)
+	p.registerPostfix(token.Decrement, p.parsePostfixUpdateExpr)
+
+	p.statementParseFns = make(map[token.TokenType]StatementParseFn)
+	p.registerStatement(token.Struct, p.parseStructDecl)
+	p.registerStatement(token.Enum, p.parseEnumDecl)
+	p.registerStatement(token.Const, p.parseConstDecl)
+	p.registerStatement(token.Let, p.parseLetStmt)
+	p.registerStatement(token.StaticAssert, p.parseStaticAssert)
+	p.registerStatement(token.For, p.parseForStmt)
+	p.registerStatement(token.Switch, p.parseSwitchStmt)
+	p.registerStatement(token.At, p.parseAttributed)
 	return p
 }
 
+// Parse is the one-call entry point for tooling: it parses src as file and returns the program together with
+// every diagnostic and comment found along the way. file may be nil, in which case positions carry no file name.
+func Parse(file *token.File, src []byte) (program *ast.Program, diags []diagnostic.Diagnostic, comments []*ast.Comment) {
+	return ParseWithOptions(file, src, Options{})
+}
+
+// ParseWithOptions is Parse with options for the parser
+func ParseWithOptions(file *token.File, src []byte, options Options) (program *ast.Program, diags []diagnostic.Diagnostic, comments []*ast.Comment) {
+	var p *Parser
+
+	// a parser bug should surface as a diagnostic, not take the caller down with it. Building the parser
+	// already lexes two tokens, so the lexer can fail before there is a parser to report through.
+	defer func() {
+		if r := recover(); r != nil {
+			program = &ast.Program{Stmts: make([]ast.Stmt, 0)}
+			if p == nil {
+				pos := token.Position{Line: 1, Column: 1}
+				if file != nil {
+					pos.Filename = file.Name
+				}
+				diags, comments = []diagnostic.Diagnostic{diagnostic.New(pos, codeInternal, "", r)}, nil
+				return
+			}
+			p.addError(p.currToken.Pos, codeInternal, p.currToken.Literal, r)
+			diags, comments = p.Diagnostics(), p.Comments()
+		}
+	}()
+
+	p = NewWithOptions(lexer.NewFile(file, string(src)), options)
+	program = p.ParseProgram()
+	return program, p.Diagnostics(), p.Comments()
+}
+
+// Errors returns just the messages of Diagnostics
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := make([]string, 0, len(p.diagnostics))
+	for _, diag := range p.diagnostics {
+		errors = append(errors, diag.Message)
+	}
+	return errors
+}
+
+func (p *Parser) Diagnostics() []diagnostic.Diagnostic {
+	return p.diagnostics
+}
+
+// Comments returns every comment seen so far in source order, they never reach the grammar itself
+func (p *Parser) Comments() []*ast.Comment {
+	return p.comments
+}
+
+// addError records code at pos, the message template for it lives in the diagnostic catalog
+func (p *Parser) addError(pos token.Position, code string, args ...interface{}) {
+	if p.abandoned {
+		return // everything after the depth or error limit is fallout from abandoning the input
+	}
+	diag := diagnostic.New(pos, code, args...)
+	if n := len(p.diagnostics); n > 0 && p.diagnostics[n-1].Pos == pos && p.diagnostics[n-1].Message == diag.Message {
+		return // the same token met again while recovering
+	}
+	p.diagnostics = append(p.diagnostics, diag)
+	p.checkErrorLimit()
+}
+
+// abandons the input once there are more than MaxErrors errors. The statement being parsed still runs to its
+// end, and ParseProgram stops there.
+func (p *Parser) checkErrorLimit() {
+	if p.options.MaxErrors > 0 && diagnostic.CountErrors(p.diagnostics) > p.options.MaxErrors {
+		p.diagnostics = diagnostic.Limit(p.diagnostics, p.options.MaxErrors)
+		p.abandoned = true
+	}
 }
 
-func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
+func (p *Parser) registerPrefix(tokenType token.TokenType, fn PrefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
 
-func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
+func (p *Parser) registerInfix(tokenType token.TokenType, fn InfixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
+func (p *Parser) registerPostfix(tokenType token.TokenType, fn PostfixParseFn) {
+	p.precedences[tokenType] = POSTFIX
+	p.postfixParseFns[tokenType] = fn
+}
+
+func (p *Parser) registerStatement(tokenType token.TokenType, fn StatementParseFn) {
+	p.statementParseFns[tokenType] = fn
+}
+
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("Honk! no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	if t == token.Illegal {
+		return // the lexer has said what is wrong with it
+	}
+	if p.lexer.IsKeyword(p.currToken.Literal) {
+		p.addError(p.currToken.Pos, codeReservedWord, p.currToken.Literal)
+		return
+	}
+	p.addError(p.currToken.Pos, codeNoPrefix, t)
 }
 
-// advances current and peek by one
+// advances current and peek by one, setting comments aside as it goes
 func (p *Parser) nextToken() {
-	p.currToken = p.peekToken
-	p.peekToken = p.lexer.NextToken()
+	p.currToken, p.currEnd = p.peekToken, p.peekEnd
+	p.peekToken, p.peekEnd = p.lexer.NextToken(), p.lexer.End()
+
+	for p.peekTokenIs(token.Comment) {
+		p.comments = append(p.comments, &ast.Comment{Pos: p.peekToken.Pos, Text: p.peekToken.Literal})
+		p.peekToken, p.peekEnd = p.lexer.NextToken(), p.lexer.End()
+	}
+
+	// the lexer's diagnostics join the parser's as the tokens they are about are read, keeping source order
+	lexed := p.lexer.Diagnostics()
+	if !p.abandoned {
+		p.diagnostics = append(p.diagnostics, lexed[p.lexed:]...)
+		p.checkErrorLimit()
+	}
+	p.lexed = len(lexed)
 }
 
 // Checks whether current token matches given type
@@ -134,31 +431,84 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	return false
 }
 
+// a statement ends at a semicolon, or at a line break when the lexer reports them
+func (p *Parser) peekTerminator() bool {
+	return p.peekTokenIs(token.Semicolon) || p.peekTokenIs(token.Newline)
+}
+
+// the tokens that, when missing, are most likely missing right after the token before, spelled as in source
+var insertable = map[token.TokenType]string{
+	token.Semicolon:          ";",
+	token.RightParen:         ")",
+	token.RightSquareBracket: "]",
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("Honk! expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	if p.peekTokenIs(token.Illegal) {
+		return // reported by the lexer
+	}
+	if t == token.Identifier && p.lexer.IsKeyword(p.peekToken.Literal) {
+		p.addError(p.peekToken.Pos, codeReservedWord, p.peekToken.Literal)
+		return
+	}
+
+	n := len(p.diagnostics)
+	p.addError(p.peekToken.Pos, codeUnexpectedToken, t, p.peekToken.Type)
+	if text, ok := insertable[t]; ok && len(p.diagnostics) > n {
+		edit := diagnostic.Edit{Pos: p.currEnd, NewText: text}
+		p.diagnostics[n].Fixes = []diagnostic.Fix{{Message: "insert " + text, Edits: []diagnostic.Edit{edit}}}
+	}
 }
 
 func (p *Parser) peekPrecedence() Precedence {
-	if p, ok := precedences[p.peekToken.Type]; ok {
+	if p, ok := p.precedences[p.peekToken.Type]; ok {
 		return p
 	}
 	return LOWEST
 }
 
 func (p *Parser) currPrecedence() Precedence {
-	if p, ok := precedences[p.currToken.Type]; ok {
+	if p, ok := p.precedences[p.currToken.Type]; ok {
 		return p
 	}
 	return LOWEST
 }
 
+// the precedence to parse the right operand of the current operator with. Parsing it one level lower for a
+// right associative operator lets the operand take in another operator of the same precedence.
+func (p *Parser) operandPrecedence() Precedence {
+	if p.associativities[p.currToken.Type] == RightAssociative {
+		return p.currPrecedence() - 1
+	}
+	return p.currPrecedence()
+}
+
+// the run of /// comments on the lines directly above line, without the slashes and one space after them
+func (p *Parser) docAbove(line int) string {
+	lines := make([]string, 0)
+	for i := len(p.comments) - 1; i >= 0; i-- {
+		comment := p.comments[i]
+		if comment.Pos.Line >= line {
+			continue // read ahead of the declaration
+		}
+		if comment.Pos.Line != line-1-len(lines) || !strings.HasPrefix(comment.Text, "///") {
+			break
+		}
+		lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(comment.Text, "///"), " "))
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
 // Parsing methods
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Stmts = make([]ast.Stmt, 0)
 
-	for !p.currTokenIs(token.EOF) {
+	for !p.currTokenIs(token.EOF) && !p.abandoned {
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Stmts = append(program.Stmts, stmt)
@@ -170,137 +520,1315 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 // Statements
 func (p *Parser) parseStatement() ast.Stmt {
+	defer p.untrace(p.trace("parseStatement"))
+
+	if p.currTokenIs(token.Newline) {
+		return nil // a line break left over after a statement that ended some other way
+	}
+	if fn, ok := p.statementParseFns[p.currToken.Type]; ok {
+		return fn()
+	}
+	if p.currTokenIs(token.Identifier) && p.currToken.Literal == "test" && p.peekTokenIs(token.String) {
+		return p.parseTestDecl() // test stays an ordinary name everywhere else
+	}
+	if p.currTokenIs(token.Identifier) && p.currToken.Literal == "pure" && p.peekTokenIs(token.Def) {
+		return p.parsePureFunction() // and so does pure
+	}
 	return p.parseExpressionStmt()
 }
 
-func (p *Parser) parseExpressionStmt() *ast.ExpressionStmt {
-	stmt := &ast.ExpressionStmt{Token: p.currToken}
+func (p *Parser) parseLetStmt() ast.Stmt {
+	defer p.untrace(p.trace("parseLetStmt"))
 
-	stmt.Expr = p.parseExpression(LOWEST)
+	stmt := &ast.LetStmt{Token: p.currToken}
 
-	if p.peekTokenIs(token.Semicolon) {
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	var ok bool
+	if stmt.Type, ok = p.parseTypeAnnotation(); !ok {
+		return nil
+	}
+
+	if !p.expectPeek(token.Assign) {
+		return nil
+	}
+	p.nextToken() // advance past =
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTerminator() {
 		p.nextToken()
 	}
 	return stmt
 }
 
-// Expressions
-func (p *Parser) parseExpression(precedence Precedence) ast.Expr {
-	prefix := p.prefixParseFns[p.currToken.Type] // look for prefix function for p.currToken
-	if prefix == nil {
-		p.noPrefixParseFnError(p.currToken.Type)
+func (p *Parser) parseConstDecl() ast.Stmt {
+	defer p.untrace(p.trace("parseConstDecl"))
+
+	stmt := &ast.ConstDecl{Token: p.currToken}
+
+	if !p.expectPeek(token.Identifier) {
 		return nil
 	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 
-	left := prefix() // call prefix function
+	if !p.expectPeek(token.Assign) {
+		return nil
+	}
+	p.nextToken() // advance past =
 
-	// if the statement has not ended and the passed in precedence is lower than the precedence of the next token
-	// if the precedence of the next token is higher, then we need to parse it as an infix expression because it is higher priority
-	// otherwise we return the expression as parsed by the prefix
-	for !p.peekTokenIs(token.Semicolon) && precedence < p.peekPrecedence() {
-		// look for an infix parse fn
-		infix := p.infixParseFns[p.peekToken.Type]
-		if infix == nil {
-			return left
-		}
+	stmt.Value = p.parseExpression(LOWEST)
 
+	if p.peekTerminator() {
 		p.nextToken()
+	}
+	return stmt
+}
 
-		// we bind left to the infix expression
-		left = infix(left)
+func (p *Parser) parseStaticAssert() ast.Stmt {
+	defer p.untrace(p.trace("parseStaticAssert"))
+
+	stmt := &ast.StaticAssert{Token: p.currToken}
+
+	if !p.expectPeek(token.LeftParen) {
+		return nil
+	}
+	args := p.parseExpressionList(token.RightParen)
+	if args == nil {
+		return nil
+	}
+	if len(args) < 1 || len(args) > 2 {
+		p.addError(stmt.Token.Pos, codeBadStaticAssert, len(args))
+		if p.peekTerminator() {
+			p.nextToken()
+		}
+		return nil
+	}
+	stmt.Condition = args[0]
+	if len(args) == 2 {
+		stmt.Message = args[1]
 	}
 
-	return left
+	if p.peekTerminator() {
+		p.nextToken()
+	}
+	return stmt
 }
 
-// prefix and infix functions
+// one or more attributes and the declaration they are on, a named function or a struct
+func (p *Parser) parseAttributed() ast.Stmt {
+	defer p.untrace(p.trace("parseAttributed"))
 
-// this is an prefixParseFn, so it will not call p.nextToken() at the end
-func (p *Parser) parseIdentifier() ast.Expr {
-	return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
-}
+	first := p.currToken
+	attrs := make([]*ast.Attribute, 0)
+	for p.currTokenIs(token.At) {
+		attr := p.parseAttribute()
+		if attr == nil {
+			return nil
+		}
+		attrs = append(attrs, attr)
 
-// this is an prefixParseFn, so it will not call p.nextToken() at the end
-func (p *Parser) parseNumberLiteral() ast.Expr {
-	literal := &ast.NumberLiteral{Token: p.currToken}
+		p.nextToken() // advance to the next attribute or the declaration
+		for p.currTokenIs(token.Newline) {
+			p.nextToken()
+		}
+	}
 
-	value, err := strconv.ParseFloat(p.currToken.Literal, 64)
+	// /// comments go above the attributes
+	doc := p.docAbove(first.Pos.Line)
 
-	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+	switch {
+	case p.currTokenIs(token.Struct):
+		stmt := p.parseStructDecl()
+		if decl, ok := stmt.(*ast.StructDecl); ok {
+			decl.Attributes, decl.Doc = attrs, doc
+		}
+		return stmt
+	case p.currTokenIs(token.Def), p.currTokenIs(token.Identifier) && p.currToken.Literal == "pure" && p.peekTokenIs(token.Def):
+		stmt := p.parseStatement()
+		if exprStmt, ok := stmt.(*ast.ExpressionStmt); ok {
+			if fn, ok := exprStmt.Expr.(*ast.FunctionLiteral); ok && fn.Name != nil {
+				fn.Attributes, fn.Doc = attrs, doc
+				return stmt
+			}
+		}
+		p.addError(first.Pos, codeBadAttributed, "an anonymous function")
+		return stmt
+	}
+
+	p.addError(p.currToken.Pos, codeBadAttributed, p.currToken.Type)
+	return nil
+}
+
+// @name or @name(args), the current token is the @
+func (p *Parser) parseAttribute() *ast.Attribute {
+	defer p.untrace(p.trace("parseAttribute"))
+
+	attr := &ast.Attribute{Token: p.currToken}
+	if !p.expectPeek(token.Identifier) {
 		return nil
 	}
+	attr.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
 
-	literal.Value = value
+	if p.peekTokenIs(token.LeftParen) {
+		p.nextToken()
+		attr.Args = p.parseExpressionList(token.RightParen)
+		if attr.Args == nil {
+			return nil
+		}
+	}
+	return attr
+}
 
-	return literal
+// pure def name(...) { ... }
+func (p *Parser) parsePureFunction() ast.Stmt {
+	defer p.untrace(p.trace("parsePureFunction"))
+
+	pure := p.currToken
+	p.nextToken() // advance to def
+
+	stmt := p.parseExpressionStmt()
+	fn, ok := stmt.Expr.(*ast.FunctionLiteral)
+	if !ok || fn.Name == nil {
+		p.addError(pure.Pos, codeBadPure)
+		return stmt
+	}
+	fn.Pure = true
+	return stmt
 }
 
-// this is an prefixParseFn, so it will not call p.nextToken() at the end
-func (p *Parser) parsePrefixExpr() ast.Expr {
-	expr := &ast.PrefixExpr{Token: p.currToken, Operator: p.currToken.Literal}
+// test "name" { ... }
+func (p *Parser) parseTestDecl() ast.Stmt {
+	defer p.untrace(p.trace("parseTestDecl"))
 
-	p.nextToken() // advance past operator
-	expr.Right = p.parseExpression(PREFIX)
+	stmt := &ast.TestDecl{Token: p.currToken}
 
-	return expr
+	p.nextToken() // advance to the name
+	stmt.Name = &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStmt()
+
+	if p.peekTerminator() {
+		p.nextToken()
+	}
+	return stmt
 }
 
-// this is an infixParseFn, so it will not call p.nextToken() at the end
-func (p *Parser) parseInfixExpr(left ast.Expr) ast.Expr {
-	expr := &ast.InfixExpr{Token: p.currToken, Operator: p.currToken.Literal, Left: left}
+// for i in 0..10 { ... }
+func (p *Parser) parseForStmt() ast.Stmt {
+	defer p.untrace(p.trace("parseForStmt"))
 
-	precedence := p.currPrecedence()
-	p.nextToken()
-	expr.Right = p.parseExpression(precedence)
+	stmt := &ast.ForStmt{Token: p.currToken}
 
-	return expr
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	stmt.Var = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.In) {
+		return nil
+	}
+	p.nextToken() // advance past in
+
+	stmt.Iterable = p.parseExpressionBeforeBlock()
+	if stmt.Iterable == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStmt()
+
+	if p.peekTerminator() {
+		p.nextToken()
+	}
+	return stmt
 }
 
-// func (p *Parser) parseBooleanLiteral() ast.Expr {
-// 	return &ast.BooleanLiteral{Token: p.currToken, Value: p.currTokenIs(token.True)}
-// }
+// switch (x) { case 1, 2: ...; default: ... }
+func (p *Parser) parseSwitchStmt() ast.Stmt {
+	defer p.untrace(p.trace("parseSwitchStmt"))
 
-// this is a prefixParseFn
-func (p *Parser) parseGroupedExpr() ast.Expr {
+	stmt := &ast.SwitchStmt{Token: p.currToken, Cases: make([]*ast.SwitchCase, 0)}
+
+	if !p.expectPeek(token.LeftParen) {
+		return nil
+	}
 	p.nextToken() // advance past (
+	stmt.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RightParen) || !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+	p.nextToken() // advance past {
 
-	expr := p.parseExpression(LOWEST)
+	for !p.currTokenIs(token.RightCurlyBracket) && !p.currTokenIs(token.EOF) {
+		if p.currTokenIs(token.Newline) {
+			p.nextToken()
+			continue
+		}
+		c := p.parseSwitchCase()
+		if c == nil {
+			return nil
+		}
+		stmt.Cases = append(stmt.Cases, c)
+	}
 
-	if !p.expectPeek(token.RightParen) {
+	if p.peekTerminator() {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// case a, b: stmts or default: stmts, leaving the current token on whatever follows the statements
+func (p *Parser) parseSwitchCase() *ast.SwitchCase {
+	defer p.untrace(p.trace("parseSwitchCase"))
+
+	c := &ast.SwitchCase{Token: p.currToken}
+	switch {
+	case p.currTokenIs(token.Case):
+		c.Values = make([]ast.Expr, 0)
+		for {
+			p.nextToken() // advance past case or ,
+			value := p.parseExpression(LOWEST)
+			if value == nil {
+				return nil
+			}
+			c.Values = append(c.Values, value)
+			if !p.peekTokenIs(token.Comma) {
+				break
+			}
+			p.nextToken()
+		}
+		if !p.expectPeek(token.Colon) {
+			return nil
+		}
+	case p.currTokenIs(token.Default):
+		if !p.expectPeek(token.Colon) {
+			return nil
+		}
+	default:
+		p.addError(p.currToken.Pos, codeUnexpectedToken, token.Case, p.currToken.Type)
 		return nil
 	}
 
-	return expr
+	c.Body = &ast.BlockStmt{Token: p.currToken, Stmts: make([]ast.Stmt, 0)}
+	p.nextToken() // advance past :
+	for !p.currTokenIs(token.Case) && !p.currTokenIs(token.Default) && !p.currTokenIs(token.RightCurlyBracket) && !p.currTokenIs(token.EOF) {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			c.Body.Stmts = append(c.Body.Stmts, stmt)
+		}
+		p.nextToken()
+	}
+	return c
 }
 
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expr {
-	list := []ast.Expr{}
+// struct Point { x: float; y: float; }
+func (p *Parser) parseStructDecl() ast.Stmt {
+	defer p.untrace(p.trace("parseStructDecl"))
 
-	if p.peekTokenIs(end) {
+	stmt := &ast.StructDecl{Token: p.currToken, Doc: p.docAbove(p.currToken.Pos.Line)}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+
+	stmt.Fields = []*ast.FieldDecl{}
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		field := &ast.FieldDecl{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+
+		if !p.expectPeek(token.Colon) || !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		field.Type = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		stmt.Fields = append(stmt.Fields, field)
+
+		// the last field may leave off its semicolon, a line break does as well as one
+		if p.peekTokenIs(token.Newline) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Semicolon) {
+			return nil
+		}
+	}
+
+	p.nextToken() // advance to }
+
+	if p.peekTerminator() {
 		p.nextToken()
-		return list
 	}
-	p.nextToken() // advance past opening toke
+	return stmt
+}
 
-	list = append(list, p.parseExpression(LOWEST))
+func (p *Parser) parseBlockStmt() *ast.BlockStmt {
+	defer p.untrace(p.trace("parseBlockStmt"))
 
-	for p.peekTokenIs(token.Comma) {
-		p.nextToken() // advance to commma
-		p.nextToken() // advance past comma
-		list = append(list, p.parseExpression(LOWEST))
+	defer p.allowStructLiteral()()
+
+	block := &ast.BlockStmt{Token: p.currToken}
+	block.Stmts = make([]ast.Stmt, 0)
+
+	p.nextToken() // advance past {
+
+	for !p.currTokenIs(token.RightCurlyBracket) && !p.currTokenIs(token.EOF) {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Stmts = append(block.Stmts, stmt)
+		}
+		p.nextToken()
 	}
 
-	if !p.expectPeek(end) {
-		return nil
+	// a block the input ends inside would otherwise pass for one closed at the end
+	if p.currTokenIs(token.EOF) {
+		n := len(p.diagnostics)
+		p.addError(p.currToken.Pos, codeUnexpectedToken, token.RightCurlyBracket, token.EOF)
+		if len(p.diagnostics) > n {
+			p.diagnostics[n].Notes = []diagnostic.Note{{Pos: block.Token.Pos, Length: 1, Message: "the block opened here"}}
+		}
 	}
 
-	return list
+	return block
 }
 
-func (p *Parser) parseCallExpr(function ast.Expr) ast.Expr {
-	expr := &ast.CallExpr{Token: p.currToken, Function: function}
+func (p *Parser) parseExpressionStmt() *ast.ExpressionStmt {
+	defer p.untrace(p.trace("parseExpressionStmt"))
+
+	stmt := &ast.ExpressionStmt{Token: p.currToken}
+
+	stmt.Expr = p.parseExpression(LOWEST)
+
+	if p.peekTerminator() {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// Expressions
+func (p *Parser) parseExpression(precedence Precedence) ast.Expr {
+	defer p.untrace(p.trace("parseExpression"))
+
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > p.options.MaxDepth {
+		p.addError(p.currToken.Pos, codeTooDeep, p.options.MaxDepth)
+		p.abandoned = true
+		// give up on the rest of the input rather than unwinding into a flood of errors
+		for !p.currTokenIs(token.EOF) {
+			p.nextToken()
+		}
+		return nil
+	}
+
+	prefix := p.prefixParseFns[p.currToken.Type] // look for prefix function for p.currToken
+	if prefix == nil {
+		p.noPrefixParseFnError(p.currToken.Type)
+		return nil
+	}
+
+	left := prefix() // call prefix function
+	if left == nil {
+		// the prefix already reported why, don't build infix nodes around nothing
+		return nil
+	}
+
+	// if the statement has not ended and the passed in precedence is lower than the precedence of the next token
+	// if the precedence of the next token is higher, then we need to parse it as an infix expression because it is higher priority
+	// otherwise we return the expression as parsed by the prefix
+	for !p.peekTokenIs(token.Semicolon) && precedence < p.peekPrecedence() {
+		if p.noStructLiteral && p.peekTokenIs(token.LeftCurlyBracket) {
+			return left
+		}
+
+		// a postfix operator applies to everything that binds tighter, which is all of left by now
+		if postfix := p.postfixParseFns[p.peekToken.Type]; postfix != nil {
+			p.nextToken()
+			if left = postfix(left); left == nil {
+				return nil
+			}
+			continue
+		}
+
+		// look for an infix parse fn
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return left
+		}
+
+		p.nextToken()
+
+		// we bind left to the infix expression
+		left = infix(left)
+		if left == nil {
+			return nil
+		}
+	}
+
+	return left
+}
+
+// prefix and infix functions
+
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseIdentifier() ast.Expr {
+	return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+}
+
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseNumberLiteral() ast.Expr {
+	literal := &ast.NumberLiteral{Token: p.currToken}
+
+	value, err := parseNumber(p.currToken.Literal)
+
+	if err != nil {
+		p.addError(p.currToken.Pos, codeBadNumber, p.currToken.Literal)
+		return nil
+	}
+
+	literal.Value = value
+
+	return literal
+}
+
+// Accepts decimal literals with optional fraction and exponent, 0x/0o/0b prefixed integers, hex floats
+// with a p exponent, and _ between digits in any of them. The result is exact, 0.1 is stored as 1/10.
+func parseNumber(literal string) (constant.Value, error) {
+	// Go's literal syntax is ours too, except that a leading 0 does not mean octal,
+	// so everything without a base prefix is read as a decimal float
+	kind := gotoken.FLOAT
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			if !strings.ContainsAny(literal, ".pP") {
+				kind = gotoken.INT
+			}
+		case 'o', 'O', 'b', 'B':
+			kind = gotoken.INT
+		}
+	}
+
+	value := constant.MakeFromLiteral(literal, kind, 0)
+	if value.Kind() == constant.Unknown {
+		return nil, fmt.Errorf("malformed number literal %q", literal)
+	}
+	if kind == gotoken.FLOAT && !strings.ContainsAny(literal, ".eEpP") {
+		value = constant.ToInt(value) // spelled as an integer, so 7 / 2 folds as integer division
+	}
+	return value, nil
+}
+
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseStringLiteral() ast.Expr {
+	defer p.untrace(p.trace("parseStringLiteral"))
+
+	segments, ok := p.lexer.Segments(p.currToken)
+	if !ok {
+		p.addError(p.currToken.Pos, codeBadInterpolation)
+		return nil
+	}
+
+	if len(segments) == 0 || (len(segments) == 1 && !segments[0].Expr) {
+		return &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+	}
+
+	expr := &ast.InterpolatedString{Token: p.currToken, Parts: make([]ast.Expr, 0, len(segments))}
+	for _, segment := range segments {
+		if segment.Expr {
+			expr.Parts = append(expr.Parts, p.parseInterpolation(segment))
+			continue
+		}
+		text := token.Token{Type: token.String, Literal: segment.Text, Pos: segment.Pos}
+		expr.Parts = append(expr.Parts, &ast.StringLiteral{Token: text, Value: segment.Text})
+	}
+	return expr
+}
+
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseRawStringLiteral() ast.Expr {
+	return &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+}
+
+// the escapes a byte string may use besides \xHH
+var byteEscapes = map[byte]byte{'n': '\n', 't': '\t', 'r': '\r', '0': 0, '\\': '\\', '"': '"'}
+
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseByteLiteral() ast.Expr {
+	value, bad := unescapeBytes(p.currToken.Literal)
+	if bad != "" {
+		p.addError(p.currToken.Pos, codeBadEscape, bad)
+		return nil
+	}
+	return &ast.ByteLiteral{Token: p.currToken, Value: value}
+}
+
+// decodes the escapes in literal, returning the first one that isn't valid if there is one
+func unescapeBytes(literal string) ([]byte, string) {
+	value := make([]byte, 0, len(literal))
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '\\' {
+			value = append(value, literal[i])
+			continue
+		}
+		if i+1 == len(literal) {
+			return nil, literal[i:]
+		}
+
+		if c, ok := byteEscapes[literal[i+1]]; ok {
+			value = append(value, c)
+			i++
+			continue
+		}
+		if literal[i+1] == 'x' && i+4 <= len(literal) {
+			if n, err := strconv.ParseUint(literal[i+2:i+4], 16, 8); err == nil {
+				value = append(value, byte(n))
+				i += 3
+				continue
+			}
+		}
+		if literal[i+1] == 'x' {
+			return nil, literal[i:minInt(i+4, len(literal))]
+		}
+		return nil, literal[i : i+2]
+	}
+	return value, ""
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// a heredoc's body has no escapes, it is taken as is
+//
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseHeredoc() ast.Expr {
+	return &ast.ByteLiteral{Token: p.currToken, Value: []byte(p.currToken.Literal)}
+}
+
+// the expression inside ${...} is parsed by a parser of its own, positioned where the segment sits in the
+// file, whose diagnostics and comments are folded back into this one
+func (p *Parser) parseInterpolation(segment lexer.Segment) ast.Expr {
+	sub := NewWithOptions(lexer.NewAt(segment.Text, segment.Pos, p.lexer.Options()), p.options)
+	sub.depth = p.depth
+	sub.traceLevel = p.traceLevel
+
+	expr := sub.parseExpression(LOWEST)
+	if expr != nil && !sub.peekTokenIs(token.EOF) {
+		sub.peekError(token.EOF)
+	}
+
+	p.abandoned = p.abandoned || sub.abandoned
+	p.diagnostics = append(p.diagnostics, sub.diagnostics...)
+	p.checkErrorLimit()
+	p.comments = append(p.comments, sub.comments...)
+	return expr
+}
+
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseNilLiteral() ast.Expr {
+	return &ast.NilLiteral{Token: p.currToken}
+}
+
+// this is an PrefixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parsePrefixExpr() ast.Expr {
+	defer p.untrace(p.trace("parsePrefixExpr"))
+
+	expr := &ast.PrefixExpr{Token: p.currToken, Operator: ast.OperatorFor(p.currToken.Type)}
+
+	p.nextToken() // advance past operator
+	expr.Right = p.parseExpression(PREFIX)
+
+	return expr
+}
+
+// this is an InfixParseFn, so it will not call p.nextToken() at the end
+func (p *Parser) parseInfixExpr(left ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseInfixExpr"))
+
+	expr := &ast.InfixExpr{Token: p.currToken, Operator: ast.OperatorFor(p.currToken.Type), Left: left}
+
+	precedence := p.currPrecedence()
+	associativity := p.associativities[p.currToken.Type]
+	operand := p.operandPrecedence()
+	p.nextToken()
+	expr.Right = p.parseExpression(operand)
+
+	// the right operand stopped short of another operator of the same precedence, which doesn't chain
+	if associativity == NonAssociative && p.peekPrecedence() == precedence {
+		p.addError(p.peekToken.Pos, codeChainedComparison, p.peekToken.Literal, expr.String())
+	}
+
+	return expr
+}
+
+// this is a PrefixParseFn, handles `++x` and `--x`
+func (p *Parser) parseUpdateExpr() ast.Expr {
+	defer p.untrace(p.trace("parseUpdateExpr"))
+
+	expr := &ast.PrefixExpr{Token: p.currToken, Operator: ast.OperatorFor(p.currToken.Type)}
+
+	p.nextToken() // advance past operator
+	expr.Right = p.parseExpression(PREFIX)
+
+	if !p.checkAssignable(expr.Right) {
+		return nil
+	}
+
+	return expr
+}
+
+// this is a PostfixParseFn, handles x++ and x--
+func (p *Parser) parsePostfixUpdateExpr(left ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parsePostfixUpdateExpr"))
+
+	if !p.checkAssignable(left) {
+		return nil
+	}
+
+	return &ast.PostfixExpr{Token: p.currToken, Left: left, Operator: ast.OperatorFor(p.currToken.Type)}
+}
+
+// this is an InfixParseFn
+func (p *Parser) parseAssignExpr(target ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseAssignExpr"))
+
+	if !p.checkAssignable(target) {
+		return nil
+	}
+
+	expr := &ast.AssignExpr{Token: p.currToken, Operator: ast.OperatorFor(p.currToken.Type), Target: target}
+
+	operand := p.operandPrecedence()
+	p.nextToken()
+	expr.Value = p.parseExpression(operand)
+
+	return expr
+}
+
+// this is an InfixParseFn, handles `cond ? a : b`
+func (p *Parser) parseConditionalExpr(condition ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseConditionalExpr"))
+
+	expr := &ast.ConditionalExpr{Token: p.currToken, Condition: condition}
+	operand := p.operandPrecedence()
+
+	p.nextToken() // advance past ?
+	expr.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.Colon) {
+		return nil
+	}
+
+	p.nextToken() // advance past :
+	expr.Alternative = p.parseExpression(operand)
+
+	return expr
+}
+
+// only names and fields can be assigned to
+func (p *Parser) checkAssignable(target ast.Expr) bool {
+	switch target.(type) {
+	case *ast.Identifier, *ast.FieldAccessExpr:
+		return true
+	case nil:
+		return false
+	default:
+		p.addError(p.currToken.Pos, codeBadAssignTarget, target.String())
+		return false
+	}
+}
+
+// this is a PrefixParseFn
+func (p *Parser) parseBooleanLiteral() ast.Expr {
+	return &ast.BooleanLiteral{Token: p.currToken, Value: p.currTokenIs(token.True)}
+}
+
+// this is a PrefixParseFn
+// (a; b; c) is a sequence rather than a grouping
+func (p *Parser) parseGroupedExpr() ast.Expr {
+	defer p.untrace(p.trace("parseGroupedExpr"))
+
+	open := p.currToken
+	p.nextToken() // advance past (
+
+	defer p.allowStructLiteral()()
+
+	expr := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.Semicolon) {
+		return p.parseSequence(open, expr)
+	}
+
+	if !p.expectPeek(token.RightParen) {
+		return nil
+	}
+
+	return expr
+}
+
+// parses the rest of a sequence after its first expression, up to and including the ). A ( whose ) was left
+// off, as in `let x = (1 + 2;` followed by more statements, would otherwise run on into those statements, so
+// when a part fails to parse or the ) is missing what the parser said about them is dropped and the missing )
+// is reported at the first ; instead, where the fix for it goes.
+func (p *Parser) parseSequence(open token.Token, first ast.Expr) ast.Expr {
+	diags, lexed := len(p.diagnostics), p.lexed
+	semicolon, end := p.peekToken, p.currEnd
+
+	seq := &ast.SequenceExpr{Token: open, Exprs: []ast.Expr{first}}
+	failed := first == nil
+	for p.peekTokenIs(token.Semicolon) {
+		p.nextToken() // advance to ;
+		p.nextToken() // advance past ;
+		expr := p.parseExpression(LOWEST)
+		failed = failed || expr == nil
+		seq.Exprs = append(seq.Exprs, expr)
+	}
+
+	if !failed && len(p.diagnostics) == diags && p.expectPeek(token.RightParen) {
+		return seq
+	}
+
+	// the lexer's diagnostics stand whatever the tokens turn out to mean
+	p.diagnostics = append(p.diagnostics[:diags], p.lexer.Diagnostics()[lexed:p.lexed]...)
+	n := len(p.diagnostics)
+	p.addError(semicolon.Pos, codeUnexpectedToken, token.RightParen, semicolon.Type)
+	if len(p.diagnostics) > n {
+		edit := diagnostic.Edit{Pos: end, NewText: insertable[token.RightParen]}
+		p.diagnostics[n].Fixes = []diagnostic.Fix{{Message: "insert )", Edits: []diagnostic.Edit{edit}}}
+	}
+	return nil
+}
+
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expr {
+	defer p.allowStructLiteral()()
+
+	list := []ast.Expr{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+	p.nextToken() // advance past opening toke
+
+	list = append(list, p.parseExpression(LOWEST))
+
+	for p.peekTokenIs(token.Comma) {
+		p.nextToken() // advance to commma
+		p.nextToken() // advance past comma
+		list = append(list, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+func (p *Parser) parseCallExpr(function ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseCallExpr"))
+
+	expr := &ast.CallExpr{Token: p.currToken, Function: function}
 	expr.Arguments = p.parseExpressionList(token.RightParen)
 	return expr
 }
+
+// this is a PrefixParseFn, handles both `def name(a, b) { ... }` and anonymous `def(a, b) { ... }`
+func (p *Parser) parseFunctionLiteral() ast.Expr {
+	defer p.untrace(p.trace("parseFunctionLiteral"))
+
+	fn := &ast.FunctionLiteral{Token: p.currToken, Doc: p.docAbove(p.currToken.Pos.Line)}
+
+	if p.peekTokenIs(token.Identifier) {
+		p.nextToken()
+		fn.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	}
+
+	if p.peekTokenIs(token.LessThan) {
+		p.nextToken()
+		if fn.TypeParams = p.parseTypeParams(); fn.TypeParams == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LeftParen) {
+		return nil
+	}
+
+	fn.Parameters, fn.Variadic = p.parseFunctionParameters()
+	if fn.Parameters == nil {
+		return nil
+	}
+
+	var ok bool
+	if fn.ReturnType, ok = p.parseTypeAnnotation(); !ok {
+		return nil
+	}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+
+	fn.Body = p.parseBlockStmt()
+
+	return fn
+}
+
+// also reports whether the last parameter is variadic, `...` may only follow the last one
+func (p *Parser) parseFunctionParameters() ([]*ast.Param, bool) {
+	params := []*ast.Param{}
+
+	if p.peekTokenIs(token.RightParen) {
+		p.nextToken()
+		return params, false
+	}
+
+	variadic := false
+	for {
+		if !p.expectPeek(token.Identifier) {
+			return nil, false
+		}
+		param := &ast.Param{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+
+		if p.peekTokenIs(token.Ellipsis) {
+			p.nextToken()
+			variadic = true
+		}
+
+		var ok bool
+		if param.Type, ok = p.parseTypeAnnotation(); !ok {
+			return nil, false
+		}
+		params = append(params, param)
+
+		if variadic || !p.peekTokenIs(token.Comma) {
+			break
+		}
+		p.nextToken() // advance to comma
+	}
+
+	if !p.expectPeek(token.RightParen) {
+		return nil, false
+	}
+
+	return params, variadic
+}
+
+// parses an optional `: Type` after the current token, a nil type with ok set means there was none
+func (p *Parser) parseTypeAnnotation() (*ast.Identifier, bool) {
+	if !p.peekTokenIs(token.Colon) {
+		return nil, true
+	}
+	p.nextToken() // advance to :
+
+	if !p.expectPeek(token.Identifier) {
+		return nil, false
+	}
+	return &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}, true
+}
+
+// parses `<T, U>` with the < as the current token
+func (p *Parser) parseTypeParams() []*ast.Identifier {
+	params := []*ast.Identifier{}
+
+	for {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		params = append(params, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+
+		if !p.peekTokenIs(token.Comma) {
+			break
+		}
+		p.nextToken() // advance to comma
+	}
+
+	if !p.expectPeek(token.GreaterThan) {
+		return nil
+	}
+	return params
+}
+
+// parses `(A, B)` with the ( as the current token, like the payload of an enum variant
+func (p *Parser) parseTypeList() []*ast.Identifier {
+	types := []*ast.Identifier{}
+
+	if p.peekTokenIs(token.RightParen) {
+		p.nextToken()
+		return types
+	}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	types = append(types, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+
+	for p.peekTokenIs(token.Comma) {
+		p.nextToken() // advance to comma
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		types = append(types, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+	}
+
+	if !p.expectPeek(token.RightParen) {
+		return nil
+	}
+
+	return types
+}
+
+// this is a PrefixParseFn, handles `\x, y -> x + y` and `\x -> { ... }`
+func (p *Parser) parseLambda() ast.Expr {
+	defer p.untrace(p.trace("parseLambda"))
+
+	fn := &ast.FunctionLiteral{Token: p.currToken}
+	fn.Parameters = []*ast.Param{}
+
+	for !p.peekTokenIs(token.Arrow) {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		param := &ast.Param{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+		fn.Parameters = append(fn.Parameters, param)
+
+		if p.peekTokenIs(token.Ellipsis) {
+			p.nextToken()
+			fn.Variadic = true
+		}
+
+		var ok bool
+		if param.Type, ok = p.parseTypeAnnotation(); !ok {
+			return nil
+		}
+
+		if fn.Variadic {
+			if !p.peekTokenIs(token.Arrow) {
+				p.peekError(token.Arrow)
+				return nil
+			}
+			break
+		}
+
+		if !p.peekTokenIs(token.Arrow) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	p.nextToken() // advance to ->
+
+	if p.peekTokenIs(token.LeftCurlyBracket) {
+		p.nextToken()
+		fn.Body = p.parseBlockStmt()
+		return fn
+	}
+
+	p.nextToken() // advance past ->
+
+	// a bare expression body is sugar for a block holding that one expression
+	stmt := &ast.ExpressionStmt{Token: p.currToken, Expr: p.parseExpression(LOWEST)}
+	fn.Body = &ast.BlockStmt{Token: stmt.Token, Stmts: []ast.Stmt{stmt}}
+
+	return fn
+}
+
+// enum Color { Red, Green, Blue } or with payloads, enum Shape { Circle(float), Rect(float, float) }
+func (p *Parser) parseEnumDecl() ast.Stmt {
+	defer p.untrace(p.trace("parseEnumDecl"))
+
+	stmt := &ast.EnumDecl{Token: p.currToken, Doc: p.docAbove(p.currToken.Pos.Line)}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+
+	stmt.Variants = []*ast.EnumVariant{}
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+		variant.Payload = []*ast.Identifier{}
+
+		if p.peekTokenIs(token.LeftParen) {
+			p.nextToken()
+			variant.Payload = p.parseTypeList()
+			if variant.Payload == nil {
+				return nil
+			}
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+
+		// allow a trailing comma
+		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	p.nextToken() // advance to }
+
+	if p.peekTerminator() {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// this is an InfixParseFn, handles `0..10` and `0..=10`
+func (p *Parser) parseRangeExpr(start ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseRangeExpr"))
+
+	expr := &ast.RangeExpr{Token: p.currToken, Start: start, Inclusive: p.currTokenIs(token.RangeInclusive)}
+
+	precedence := p.currPrecedence()
+	associativity := p.associativities[p.currToken.Type]
+	expr.End = p.ParseOperand()
+
+	if associativity == NonAssociative && p.peekPrecedence() == precedence {
+		p.addError(p.peekToken.Pos, codeChainedComparison, p.peekToken.Literal, expr.String())
+	}
+
+	return expr
+}
+
+// this is an InfixParseFn, handles `x as int`
+func (p *Parser) parseCastExpr(value ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseCastExpr"))
+
+	expr := &ast.CastExpr{Token: p.currToken, Value: value}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	expr.Type = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	return expr
+}
+
+// this is an InfixParseFn, handles `Point { x: 1, y: 2 }`
+func (p *Parser) parseStructLiteral(left ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseStructLiteral"))
+
+	ident, ok := left.(*ast.Identifier)
+	if !ok {
+		p.addError(p.currToken.Pos, codeBadStructLiteral, left.String())
+		return nil
+	}
+
+	lit := &ast.StructLiteral{Token: p.currToken, Type: ident}
+	lit.Fields = []*ast.FieldValue{}
+
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		field := &ast.FieldValue{Name: &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}}
+
+		if !p.expectPeek(token.Colon) {
+			return nil
+		}
+		p.nextToken() // advance past :
+		field.Value = p.parseExpression(LOWEST)
+		lit.Fields = append(lit.Fields, field)
+
+		// allow a trailing comma
+		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	p.nextToken() // advance to }
+
+	return lit
+}
+
+// this is an InfixParseFn, handles `point.x`
+func (p *Parser) parseFieldAccessExpr(object ast.Expr) ast.Expr {
+	defer p.untrace(p.trace("parseFieldAccessExpr"))
+
+	expr := &ast.FieldAccessExpr{Token: p.currToken, Object: object}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	expr.Field = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	return expr
+}
+
+// parses the head of a block construct, where `x {` must not become a struct literal
+func (p *Parser) parseExpressionBeforeBlock() ast.Expr {
+	outer := p.noStructLiteral
+	p.noStructLiteral = true
+	expr := p.parseExpression(LOWEST)
+	p.noStructLiteral = outer
+	return expr
+}
+
+// allows struct literals until the returned function is called, for what sits inside brackets of its own in
+// the head of a block construct, like the argument in `match f(P { x: 1 }) { ... }`. A { there can't start the
+// construct's block, so it is unambiguous again. Use it as defer p.allowStructLiteral()().
+func (p *Parser) allowStructLiteral() func() {
+	outer := p.noStructLiteral
+	p.noStructLiteral = false
+	return func() { p.noStructLiteral = outer }
+}
+
+// this is a PrefixParseFn, handles `match x { 0 => a, n => { b }, _ => c }`
+func (p *Parser) parseMatchExpr() ast.Expr {
+	defer p.untrace(p.trace("parseMatchExpr"))
+
+	expr := &ast.MatchExpr{Token: p.currToken}
+
+	p.nextToken() // advance past match
+	expr.Subject = p.parseExpressionBeforeBlock()
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+	defer p.allowStructLiteral()() // the arms are inside the braces
+
+	expr.Arms = []*ast.MatchArm{}
+	for !p.peekTokenIs(token.RightCurlyBracket) {
+		p.nextToken()
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.FatArrow) {
+			return nil
+		}
+
+		if p.peekTokenIs(token.LeftCurlyBracket) {
+			p.nextToken()
+			arm.Body = p.parseBlockStmt()
+		} else {
+			p.nextToken() // advance past =>
+			stmt := &ast.ExpressionStmt{Token: p.currToken, Expr: p.parseExpression(LOWEST)}
+			arm.Body = &ast.BlockStmt{Token: stmt.Token, Stmts: []ast.Stmt{stmt}}
+		}
+		expr.Arms = append(expr.Arms, arm)
+
+		// arms are separated by commas, a trailing one is fine
+		if !p.peekTokenIs(token.RightCurlyBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	p.nextToken() // advance to }
+
+	return expr
+}
+
+// this is a PrefixParseFn, handles `try { a } catch (e) { b }` and `try { a } catch { b }`
+func (p *Parser) parseTryExpr() ast.Expr {
+	defer p.untrace(p.trace("parseTryExpr"))
+
+	expr := &ast.TryExpr{Token: p.currToken}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+	expr.Body = p.parseBlockStmt()
+
+	if !p.expectPeek(token.Catch) {
+		return nil
+	}
+
+	// the error binding is optional, catch { ... } handles the error without naming it
+	if p.peekTokenIs(token.LeftParen) {
+		p.nextToken()
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		expr.Err = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+		if !p.expectPeek(token.RightParen) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LeftCurlyBracket) {
+		return nil
+	}
+	expr.Handler = p.parseBlockStmt()
+
+	return expr
+}
+
+// a pattern is a (possibly negated) number literal, nil, true or false, an identifier to bind, _, an enum
+// variant like Color.Red, or a variant destructuring its payload like Shape.Circle(r)
+func (p *Parser) parsePattern() ast.Expr {
+	defer p.untrace(p.trace("parsePattern"))
+
+	switch p.currToken.Type {
+	case token.Number:
+		return p.parseNumberLiteral()
+	case token.Nil:
+		return p.parseNilLiteral()
+	case token.True, token.False:
+		return p.parseBooleanLiteral()
+	case token.Minus:
+		if p.peekTokenIs(token.Number) {
+			return p.parsePrefixExpr()
+		}
+	case token.Identifier:
+		return p.parseVariantPattern()
+	}
+
+	p.addError(p.currToken.Pos, codeBadPattern, p.currToken.Type)
+	return nil
+}
+
+func (p *Parser) parseVariantPattern() ast.Expr {
+	var pattern ast.Expr = p.parseIdentifier()
+
+	for p.peekTokenIs(token.Dot) {
+		p.nextToken()
+		pattern = p.parseFieldAccessExpr(pattern)
+		if pattern == nil {
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(token.LeftParen) {
+		return pattern
+	}
+
+	p.nextToken()
+	call := &ast.CallExpr{Token: p.currToken, Function: pattern, Arguments: []ast.Expr{}}
+	for !p.peekTokenIs(token.RightParen) {
+		p.nextToken()
+		arg := p.parsePattern()
+		if arg == nil {
+			return nil
+		}
+		call.Arguments = append(call.Arguments, arg)
+
+		if !p.peekTokenIs(token.RightParen) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+	p.nextToken() // advance to )
+
+	return call
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"llvm-lang/ast"
+	"llvm-lang/diagnostic"
+	"llvm-lang/lexer"
+	"testing"
+)
+
+func TestSequence(t *testing.T) {
+	program, diags, _ := Parse(nil, []byte("let a = (f(); g(); 3);"))
+	if len(diags) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if got, want := program.String(), "let a = (f(); g(); 3);"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// a ( left unclosed before a ; must not run on into the statements after it, and its fix goes before the ;
+func TestUnclosedParenBeforeSemicolon(t *testing.T) {
+	src := []byte("let b = (1 + 2;\nlet d = 4;\n")
+	_, diags, _ := Parse(nil, src)
+	if len(diags) != 1 || diags[0].Pos.Line != 1 || diags[0].Pos.Column != 15 {
+		t.Fatalf("want one diagnostic at 1:15, got %v", diags)
+	}
+
+	fixed, unfixed := diagnostic.ApplyFixes(src, diags)
+	if len(unfixed) > 0 {
+		t.Errorf("fixes not made: %v", unfixed)
+	}
+	if got, want := string(fixed), "let b = (1 + 2);\nlet d = 4;\n"; got != want {
+		t.Errorf("fixed to %q, want %q", got, want)
+	}
+}
+
+func TestUnclosedBlock(t *testing.T) {
+	tests := []struct {
+		src       string
+		line, col int // of the error, at the end of the input
+		open      int // column of the { the note points at
+	}{
+		{"def f() { 1;", 1, 13, 9},
+		{"def f() {\n    1;\n", 3, 1, 9},
+		{"def f() { for i in 0..3 { i; }", 1, 31, 9},
+		{"def f() { for i in 0..3 { i;", 1, 29, 25},
+	}
+	for _, test := range tests {
+		_, diags, _ := Parse(nil, []byte(test.src))
+		if len(diags) != 1 || diags[0].Pos.Line != test.line || diags[0].Pos.Column != test.col {
+			t.Errorf("%q: want one diagnostic at %d:%d, got %v", test.src, test.line, test.col, diags)
+			continue
+		}
+		if notes := diags[0].Notes; len(notes) != 1 || notes[0].Pos.Column != test.open {
+			t.Errorf("%q: want a note at the { in column %d, got %v", test.src, test.open, notes)
+		}
+	}
+
+	for _, src := range []string{"def f() { 1; }", "def f() { }", "def f() { for i in 0..3 { i; } }"} {
+		if _, diags, _ := Parse(nil, []byte(src)); len(diags) > 0 {
+			t.Errorf("%q: %v", src, diags)
+		}
+	}
+}
+
+// inside brackets of their own, struct literals are allowed in the head of a block construct
+func TestStructLiteralInBlockHead(t *testing.T) {
+	tests := []struct {
+		src     string
+		structs int // how many struct literals it holds
+	}{
+		{"match f(P { x: 1 }) { _ => 0 }", 1},
+		{"for i in g(P { x: 1 }) { }", 1},
+		{"for i in g(1, P { x: P { y: 2 } }) { }", 2},
+		{"match (P { x: 1 }) { _ => 0 }", 1},
+		{"for i in h(\\x -> { P { x: x } }) { }", 1},
+		{"for i in match a { _ => P { x: 1 } } { }", 1},
+		{"for i in xs { P { x: i } }", 1},
+		{"match p { _ => 0 }", 0},
+	}
+	for _, test := range tests {
+		program, diags, _ := Parse(nil, []byte(test.src))
+		if len(diags) > 0 {
+			t.Errorf("%q: %v", test.src, diags)
+			continue
+		}
+		structs := 0
+		ast.Inspect(program, func(node ast.Node) bool {
+			if _, ok := node.(*ast.StructLiteral); ok {
+				structs++
+			}
+			return true
+		})
+		if structs != test.structs {
+			t.Errorf("%q has %d struct literals, want %d", test.src, structs, test.structs)
+		}
+	}
+}
+
+func TestPostfixUpdate(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // the program printed back, or the error
+	}{
+		{"x++;", "(x++)"},
+		{"x--;", "(x--)"},
+		{"p.x++;", "(p.x++)"},
+		{"y = x++ + 1;", "(y = ((x++) + 1))"},
+		{"-x++;", "(-(x++))"},
+		{"x ** y--;", "(x ** (y--))"},
+		{"++x;", "(++x)"},
+		{"1++;", "Honk! cannot assign to 1"},
+		{"f()--;", "Honk! cannot assign to f()"},
+		{"x++++;", "Honk! cannot assign to (x++)"},
+	}
+	for _, test := range tests {
+		program, diags, _ := Parse(nil, []byte(test.src))
+		got := program.String()
+		if len(diags) > 0 {
+			got = diags[0].Message
+		}
+		if len(diags) > 1 || got != test.want {
+			t.Errorf("%q gave %q and %v, want %q", test.src, got, diags, test.want)
+		}
+	}
+}
+
+// with Options.Newlines, a line ending in a literal or a postfix operator ends its statement even when the
+// next line could carry on
+func TestNewlineEndsStatement(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"let b = true\n-1", "let b = true;(-1)"},
+		{"let b = false\n(1)", "let b = false;1"},
+		{"let b = true\n&& false", "let b = (true && false);"},
+		{"x++\n-y", "(x++)(-y)"},
+		{"x--\n(y)", "(x--)y"},
+		{"x = 1\n++y", "(x = 1)(++y)"},
+	}
+	for _, test := range tests {
+		p := New(lexer.NewWithOptions(test.src, lexer.Options{Newlines: true}))
+		program := p.ParseProgram()
+		if diags := p.Diagnostics(); len(diags) > 0 {
+			t.Errorf("%q: %v", test.src, diags)
+			continue
+		}
+		if got := program.String(); got != test.want {
+			t.Errorf("%q parsed as %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+// a static_assert with the wrong number of arguments is one error, not a second one at its ;
+func TestStaticAssertArguments(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string // the error, empty when it is well formed
+	}{
+		{`static_assert(true);`, ""},
+		{`static_assert(true, "message");`, ""},
+		{`static_assert();`, "Honk! static_assert takes a condition and an optional message, got 0 arguments"},
+		{`static_assert(true, "message", 3);`, "Honk! static_assert takes a condition and an optional message, got 3 arguments"},
+	}
+	for _, test := range tests {
+		_, diags, _ := Parse(nil, []byte(test.src+"\nlet x = 1;"))
+		if test.want == "" {
+			if len(diags) > 0 {
+				t.Errorf("%s: %v", test.src, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 || diags[0].Code != codeBadStaticAssert || diags[0].Message != test.want {
+			t.Errorf("%s: want %s %s, got %v", test.src, codeBadStaticAssert, test.want, diags)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TraceEnv turns tracing on for parsers whose Options.Trace is unset, any non-empty value sends it to stderr
+const TraceEnv = "LLVM_LANG_TRACE_PARSER"
+
+// trace logs entry into a parse function and returns the name for untrace, so a traced function starts with
+//
+//	defer p.untrace(p.trace("parseExpression"))
+func (p *Parser) trace(name string) string {
+	if p.options.Trace == nil {
+		return name
+	}
+
+	fmt.Fprintf(p.options.Trace, "%sBEGIN %s %s %s %q\n", strings.Repeat("\t", p.traceLevel), name, p.currToken.Pos, p.currToken.Type, p.currToken.Literal)
+	p.traceLevel++
+	return name
+}
+
+func (p *Parser) untrace(name string) {
+	if p.options.Trace == nil {
+		return
+	}
+
+	p.traceLevel--
+	fmt.Fprintf(p.options.Trace, "%sEND %s\n", strings.Repeat("\t", p.traceLevel), name)
+}
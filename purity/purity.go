@@ -0,0 +1,166 @@
+// Package purity works out which named functions are pure: calling one has no effect beyond returning a value
+// that depends only on its arguments, so calls with the same arguments can be cached or merged.
+//
+// A function is impure if it assigns to anything declared outside it, calls something that isn't a function
+// defined in the program, like a builtin that does I/O or a function passed in as an argument, or calls an
+// impure function. Lambdas count as part of the function they appear in. The analysis is conservative, some
+// functions it calls impure never do anything impure when run.
+package purity
+
+import (
+	"fmt"
+	"llvm-lang/ast"
+	"llvm-lang/index"
+	"llvm-lang/token"
+)
+
+// A Report is what the analysis found out about one function
+type Report struct {
+	Function *ast.FunctionLiteral
+	Pure     bool
+
+	// for impure functions, the first thing found that makes them so and where it is, phrased to follow
+	// "f is not pure because it"
+	Reason string
+	Pos    token.Position
+}
+
+// Analyze reports on every named function in program
+func Analyze(program *ast.Program) map[*ast.FunctionLiteral]*Report {
+	ix := index.Build(program)
+	reports := make(map[*ast.FunctionLiteral]*Report)
+	functions := make(map[*index.Symbol]*ast.FunctionLiteral)
+	calls := make(map[*ast.FunctionLiteral][]call)
+	order := make([]*ast.FunctionLiteral, 0) // definition order, so the reasons given don't vary from run to run
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fn, ok := node.(*ast.FunctionLiteral); ok && fn.Name != nil {
+			if sym, ok := ix.Lookup(fn.Name); ok {
+				functions[sym] = fn
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fn, ok := node.(*ast.FunctionLiteral); ok && fn.Name != nil {
+			a := &analysis{index: ix, functions: functions, local: make(map[*ast.Identifier]bool), report: &Report{Function: fn, Pure: true}}
+			a.function(fn)
+			reports[fn] = a.report
+			calls[fn] = a.calls
+			order = append(order, fn)
+		}
+		return true
+	})
+
+	// impurity spreads from callees to callers until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range order {
+			report := reports[fn]
+			if !report.Pure {
+				continue
+			}
+			for _, c := range calls[fn] {
+				if !reports[c.callee].Pure {
+					report.Pure = false
+					report.Reason = fmt.Sprintf("calls %s, which is not pure", c.callee.Name.Value)
+					report.Pos = c.pos
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return reports
+}
+
+type call struct {
+	callee *ast.FunctionLiteral
+	pos    token.Position
+}
+
+// the state while looking through one function
+type analysis struct {
+	index     *index.Index
+	functions map[*index.Symbol]*ast.FunctionLiteral
+	local     map[*ast.Identifier]bool // definitions inside the function, its parameters included
+	report    *Report
+	calls     []call // to functions defined in the program, their purity is settled afterwards
+}
+
+func (a *analysis) impure(pos token.Position, format string, args ...interface{}) {
+	if a.report.Pure {
+		a.report.Pure = false
+		a.report.Reason = fmt.Sprintf(format, args...)
+		a.report.Pos = pos
+	}
+}
+
+func (a *analysis) function(fn *ast.FunctionLiteral) {
+	// definitions first, so uses of names declared later in the function are still local
+	ast.Inspect(fn, func(node ast.Node) bool {
+		if inner, ok := node.(*ast.FunctionLiteral); ok && inner != fn && inner.Name != nil {
+			return false // analyzed on its own
+		}
+		if ident, ok := node.(*ast.Identifier); ok {
+			if sym, ok := a.index.Lookup(ident); ok && sym.Def == ident {
+				a.local[ident] = true
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			return n.Name == nil
+		case *ast.AssignExpr:
+			a.assign(n.Target)
+		case *ast.PrefixExpr:
+			if n.Operator == ast.OpIncrement || n.Operator == ast.OpDecrement {
+				a.assign(n.Right)
+			}
+		case *ast.PostfixExpr:
+			a.assign(n.Left)
+		case *ast.CallExpr:
+			a.call(n)
+		}
+		return true
+	})
+}
+
+// assigning to a field changes the object it belongs to, so what matters is where that was declared
+func (a *analysis) assign(target ast.Expr) {
+	for {
+		switch t := target.(type) {
+		case *ast.FieldAccessExpr:
+			target = t.Object
+			continue
+		case *ast.Identifier:
+			if sym, ok := a.index.Lookup(t); ok && !a.local[sym.Def] {
+				a.impure(t.Token.Pos, "assigns to %s, which is declared outside it", t.Value)
+			}
+		}
+		return
+	}
+}
+
+func (a *analysis) call(c *ast.CallExpr) {
+	ident, ok := c.Function.(*ast.Identifier)
+	if !ok {
+		a.impure(c.Token.Pos, "calls %s, which could be anything", c.Function.String())
+		return
+	}
+
+	sym, ok := a.index.Lookup(ident)
+	switch {
+	case !ok:
+		a.impure(ident.Token.Pos, "calls %s, which is not defined in the program", ident.Value)
+	case a.functions[sym] != nil:
+		a.calls = append(a.calls, call{callee: a.functions[sym], pos: ident.Token.Pos})
+	default:
+		a.impure(ident.Token.Pos, "calls %s, which could be anything", ident.Value)
+	}
+}
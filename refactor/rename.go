@@ -0,0 +1,115 @@
+// Package refactor computes source edits for automated code changes, starting with renaming a symbol.
+package refactor
+
+import (
+	"errors"
+	"fmt"
+	"llvm-lang/ast"
+	"llvm-lang/chars"
+	"llvm-lang/index"
+	"llvm-lang/lexer"
+	"llvm-lang/token"
+	"sort"
+	"unicode/utf8"
+)
+
+var (
+	ErrNoSymbol    = errors.New("no symbol at position")
+	ErrInvalidName = errors.New("not a valid identifier")
+	ErrCollision   = errors.New("new name collides with another symbol")
+)
+
+// An Edit replaces Length bytes of the source starting at Pos.Offset with NewText
+type Edit struct {
+	Pos     token.Position
+	Length  int
+	NewText string
+}
+
+// Rename returns the edits renaming the symbol under pos, its definition and every reference, to newName.
+// It refuses names that aren't identifiers or are keywords, and renames that would change what any
+// identifier in the program refers to, like capturing a reference to an outer symbol of the same name.
+func Rename(program *ast.Program, pos token.Position, newName string) ([]Edit, error) {
+	if err := checkName(newName); err != nil {
+		return nil, err
+	}
+
+	before := index.Build(program)
+	_, sym, ok := before.At(pos)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", pos, ErrNoSymbol)
+	}
+
+	idents := append([]*ast.Identifier{sym.Def}, sym.Refs...)
+	if err := checkCollision(program, before, sym, idents, newName); err != nil {
+		return nil, err
+	}
+
+	edits := make([]Edit, 0, len(idents))
+	for _, ident := range idents {
+		edits = append(edits, Edit{Pos: ident.Token.Pos, Length: len(ident.Token.Literal), NewText: newName})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Pos.Offset < edits[j].Pos.Offset })
+
+	return edits, nil
+}
+
+func checkName(name string) error {
+	if name == "" || lexer.LookupIdent(name) != token.Identifier {
+		return fmt.Errorf("%q: %w", name, ErrInvalidName)
+	}
+	for i := 0; i < len(name); i++ {
+		c := rune(name[i])
+		if c >= utf8.RuneSelf || !chars.IsIdentContinue(c) || (i == 0 && !chars.IsIdentStart(c)) {
+			return fmt.Errorf("%q: %w", name, ErrInvalidName)
+		}
+	}
+	return nil
+}
+
+// re-resolves the program with the rename applied to the tree and checks that the new name isn't defined in
+// the same scope already and that every identifier still refers to the same definition as before. The tree is
+// restored before returning.
+func checkCollision(program *ast.Program, before *index.Index, sym *index.Symbol, idents []*ast.Identifier, newName string) error {
+	for _, ident := range idents {
+		ident.Value = newName
+	}
+	after := index.Build(program)
+	for _, ident := range idents {
+		ident.Value = sym.Name
+	}
+
+	renamed, _ := after.Lookup(sym.Def)
+	for _, other := range after.Symbols {
+		if other != renamed && (renamed.Redeclares == other || other.Redeclares == renamed) {
+			return fmt.Errorf("%q: %w, %s is already defined in the same scope at %s", newName, ErrCollision, newName, other.Def.Token.Pos)
+		}
+	}
+	for _, ident := range before.Unresolved {
+		if _, ok := after.Lookup(ident); ok {
+			return fmt.Errorf("%q: %w, the undeclared %s at %s would refer to it", newName, ErrCollision, newName, ident.Token.Pos)
+		}
+	}
+	for _, ident := range before.Identifiers() {
+		old, _ := before.Lookup(ident)
+		if now, ok := after.Lookup(ident); !ok || now.Def != old.Def {
+			return fmt.Errorf("%q: %w, %s at %s would refer to a different definition", newName, ErrCollision, ident.Value, ident.Token.Pos)
+		}
+	}
+	return nil
+}
+
+// Apply returns src with edits made, the edits must not overlap
+func Apply(src []byte, edits []Edit) []byte {
+	sorted := append([]Edit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Pos.Offset < sorted[j].Pos.Offset })
+
+	out := make([]byte, 0, len(src))
+	last := 0
+	for _, edit := range sorted {
+		out = append(out, src[last:edit.Pos.Offset]...)
+		out = append(out, edit.NewText...)
+		last = edit.Pos.Offset + edit.Length
+	}
+	return append(out, src[last:]...)
+}
@@ -0,0 +1,133 @@
+package refactor
+
+import (
+	"errors"
+	"llvm-lang/parser"
+	"llvm-lang/token"
+	"strings"
+	"testing"
+)
+
+// renames the symbol at line:column of src to newName and returns the renamed source
+func rename(t *testing.T, src string, line, column int, newName string) (string, error) {
+	t.Helper()
+	program, diags, _ := parser.Parse(token.NewFile("rename.lang", []byte(src)), []byte(src))
+	if len(diags) > 0 {
+		t.Fatalf("%s doesn't parse: %v", src, diags)
+	}
+	edits, err := Rename(program, token.Position{Filename: "rename.lang", Line: line, Column: column}, newName)
+	if err != nil {
+		return "", err
+	}
+	return string(Apply([]byte(src), edits)), nil
+}
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		src          string
+		line, column int
+		newName      string
+		want         string
+	}{
+		{
+			src:  "let x = 1;\nlet y = x + x;\n",
+			line: 1, column: 5, newName: "count",
+			want: "let count = 1;\nlet y = count + count;\n",
+		},
+		{
+			src:  "def f(a: int): int { a * 2 }\nlet y = f(1);\n",
+			line: 2, column: 9, newName: "double",
+			want: "def double(a: int): int { a * 2 }\nlet y = double(1);\n",
+		},
+		{
+			// g only exists inside h, so f can take its name at the top level
+			src:  "def f() { 1 }\ndef h() { let g = 2; g }\nlet y = f();\n",
+			line: 1, column: 5, newName: "g",
+			want: "def g() { 1 }\ndef h() { let g = 2; g }\nlet y = g();\n",
+		},
+	}
+	for _, test := range tests {
+		got, err := rename(t, test.src, test.line, test.column, test.newName)
+		if err != nil {
+			t.Errorf("renaming %d:%d of %q to %s: %v", test.line, test.column, test.src, test.newName, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("renaming %d:%d of %q to %s gave %q, want %q", test.line, test.column, test.src, test.newName, got, test.want)
+		}
+	}
+}
+
+func TestRenameRefused(t *testing.T) {
+	tests := []struct {
+		src          string
+		line, column int
+		newName      string
+		err          error
+		want         string
+	}{
+		{
+			src:  "def f() { 1 }\ndef g() { 2 }\n",
+			line: 1, column: 5, newName: "g",
+			err:  ErrCollision,
+			want: `"g": new name collides with another symbol, g is already defined in the same scope at rename.lang:2:5`,
+		},
+		{
+			src:  "let a = 1;\nlet b = 2;\n",
+			line: 2, column: 5, newName: "a",
+			err:  ErrCollision,
+			want: `"a": new name collides with another symbol, a is already defined in the same scope at rename.lang:1:5`,
+		},
+		{
+			// y inside f means the outer y, and would find the renamed x instead
+			src:  "let y = 1;\ndef f() { let x = 2; x + y }\n",
+			line: 2, column: 15, newName: "y",
+			err:  ErrCollision,
+			want: `"y": new name collides with another symbol, y at rename.lang:2:26 would refer to a different definition`,
+		},
+		{
+			src:  "let x = 1;\nlet y = z;\n",
+			line: 1, column: 5, newName: "z",
+			err:  ErrCollision,
+			want: `"z": new name collides with another symbol, the undeclared z at rename.lang:2:9 would refer to it`,
+		},
+		{
+			src:  "let x = 1;\n",
+			line: 1, column: 5, newName: "match",
+			err: ErrInvalidName,
+		},
+		{
+			src:  "let x = 1;\n",
+			line: 1, column: 5, newName: "2x",
+			err: ErrInvalidName,
+		},
+		{
+			src:  "let x = 1;\n",
+			line: 1, column: 1, newName: "y",
+			err: ErrNoSymbol,
+		},
+	}
+	for _, test := range tests {
+		_, err := rename(t, test.src, test.line, test.column, test.newName)
+		if !errors.Is(err, test.err) {
+			t.Errorf("renaming %d:%d of %q to %s: got error %v, want %v", test.line, test.column, test.src, test.newName, err, test.err)
+			continue
+		}
+		if test.want != "" && err.Error() != test.want {
+			t.Errorf("renaming %d:%d of %q to %s:\ngot  %s\nwant %s", test.line, test.column, test.src, test.newName, err, test.want)
+		}
+	}
+}
+
+// a refused rename leaves the tree as it found it
+func TestRenameRefusedRestoresTree(t *testing.T) {
+	src := "def f() { 1 }\ndef g() { 2 }\nlet y = f();\n"
+	program, _, _ := parser.Parse(token.NewFile("rename.lang", []byte(src)), []byte(src))
+	before := program.String()
+	if _, err := Rename(program, token.Position{Filename: "rename.lang", Line: 1, Column: 5}, "g"); err == nil {
+		t.Fatal("renaming f to g was allowed")
+	}
+	if after := program.String(); after != before || !strings.Contains(after, "f()") {
+		t.Errorf("the tree changed from %q to %q", before, after)
+	}
+}
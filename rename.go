@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"llvm-lang/diagnostic"
+	"llvm-lang/parser"
+	"llvm-lang/refactor"
+	"llvm-lang/token"
+	"os"
+)
+
+// llvm-lang rename [-w] file line:column newName
+func runRename(args []string) int {
+	flags := flag.NewFlagSet("rename", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result back to the file instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: llvm-lang rename [-w] file line:column newName\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return 2
+	}
+	path, newName := flags.Arg(0), flags.Arg(2)
+
+	pos := token.Position{Filename: path}
+	if _, err := fmt.Sscanf(flags.Arg(1), "%d:%d", &pos.Line, &pos.Column); err != nil {
+		fmt.Fprintf(os.Stderr, "bad position %q, want line:column\n", flags.Arg(1))
+		return 2
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	program, diags, _ := parser.Parse(token.NewFile(path, src), src)
+	if report(string(src), diags, nil, false, diagnostic.DefaultLocale, diagnostic.FormatText) {
+		return 1 // renaming a tree with holes in it could miss references
+	}
+
+	edits, err := refactor.Rename(program, pos, newName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	out := refactor.Apply(src, edits)
+	if *write {
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	os.Stdout.Write(out)
+	return 0
+}
@@ -0,0 +1,239 @@
+// Package sema enforces the rules the parser can't see: constants are never assigned to, variables are declared
+// with let before they are assigned, a scope doesn't declare the same name twice, functions declared pure
+// are, attributes are ones package attribute knows, used as it says, and switch cases are distinct literals.
+package sema
+
+import (
+	"go/constant"
+	"llvm-lang/ast"
+	"llvm-lang/attribute"
+	"llvm-lang/diagnostic"
+	"llvm-lang/index"
+	"llvm-lang/purity"
+	"llvm-lang/token"
+	"strings"
+)
+
+const (
+	codeAssignConst  = "E0200"
+	codeUndeclared   = "E0201"
+	codeNotPure      = "E0202"
+	codeBadAttr      = "E0203"
+	codeAttrClash    = "E0204"
+	codeDeprecated   = "W0208"
+	codeCaseValue    = "E0205"
+	codeCaseTwice    = "E0206"
+	codeDefaultTwice = "E0207"
+	codeEmptyCase    = "E0208"
+	codeRedeclared   = "W0200"
+	codeExportTwice  = "E0209"
+)
+
+// Check returns the problems it finds in program, redeclarations are warnings and the rest errors
+func Check(program *ast.Program) []diagnostic.Diagnostic {
+	ix := index.Build(program)
+	diags := make([]diagnostic.Diagnostic, 0)
+
+	for _, sym := range ix.Symbols {
+		// hoisting defines a function before anything else in its scope, so the redeclaration is whichever comes second
+		if earlier := sym.Redeclares; earlier != nil {
+			first, second := earlier, sym
+			if first.Def.Token.Pos.Offset > second.Def.Token.Pos.Offset {
+				first, second = second, first
+			}
+			diag := diagnostic.Warn(second.Def.Token.Pos, codeRedeclared, sym.Name)
+			diag.Length = len(sym.Name)
+			diag.Notes = []diagnostic.Note{{Pos: first.Def.Token.Pos, Length: len(sym.Name), Message: "the earlier declaration"}}
+			diags = append(diags, diag)
+		}
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.AssignExpr:
+			if target, ok := n.Target.(*ast.Identifier); ok {
+				diags = checkAssign(ix, target, diags)
+			}
+		case *ast.PrefixExpr:
+			if target, ok := n.Right.(*ast.Identifier); ok && (n.Operator == ast.OpIncrement || n.Operator == ast.OpDecrement) {
+				diags = checkAssign(ix, target, diags)
+			}
+		case *ast.PostfixExpr:
+			if target, ok := n.Left.(*ast.Identifier); ok {
+				diags = checkAssign(ix, target, diags)
+			}
+		case *ast.SwitchStmt:
+			diags = checkSwitch(n, diags)
+		}
+		return true
+	})
+
+	diags = checkAttributes(program, ix, diags)
+
+	for fn, report := range purity.Analyze(program) {
+		if fn.Pure && !report.Pure {
+			diags = append(diags, diagnostic.New(report.Pos, codeNotPure, fn.Name.Value, "it "+report.Reason))
+		}
+	}
+	diagnostic.Sort(diags)
+
+	return diags
+}
+
+// validates every attribute, and warns at each use of a declaration marked @deprecated
+func checkAttributes(program *ast.Program, ix *index.Index, diags []diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	check := func(name *ast.Identifier, attrs []*ast.Attribute, target attribute.Target) {
+		for i, attr := range attrs {
+			if err := attribute.Validate(attr, target); err != nil {
+				diags = append(diags, diagnostic.New(attr.Token.Pos, codeBadAttr, attr.Name.Value, err))
+				continue
+			}
+			spec, _ := attribute.Lookup(attr.Name.Value)
+			for _, other := range attrs[:i] {
+				if other.Name.Value == attr.Name.Value {
+					diags = append(diags, diagnostic.New(attr.Token.Pos, codeBadAttr, attr.Name.Value, "it is given twice"))
+				}
+				for _, conflict := range spec.Conflicts {
+					if other.Name.Value == conflict {
+						diags = append(diags, diagnostic.New(attr.Token.Pos, codeAttrClash, conflict, attr.Name.Value))
+					}
+				}
+			}
+		}
+
+		deprecated, ok := attribute.Find(attrs, "deprecated")
+		sym, defined := ix.Lookup(name)
+		if !ok || !defined {
+			return
+		}
+		reason := ""
+		if len(deprecated.Args) > 0 {
+			message, _ := attribute.StringArg(deprecated, 0)
+			reason = ": " + message
+		}
+		for _, ref := range sym.Refs {
+			diags = append(diags, diagnostic.Warn(ref.Token.Pos, codeDeprecated, sym.Name, reason))
+		}
+	}
+
+	exporters := make(map[string]*ast.FunctionLiteral) // C name -> the first function exported under it
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			if n.Name != nil {
+				check(n.Name, n.Attributes, attribute.Function)
+				diags = checkExportName(n, exporters, diags)
+			}
+		case *ast.StructDecl:
+			check(n.Name, n.Attributes, attribute.Struct)
+		}
+		return true
+	})
+	return diags
+}
+
+// two functions exported under the same C name would clash at link time
+func checkExportName(fn *ast.FunctionLiteral, exporters map[string]*ast.FunctionLiteral, diags []diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	export, ok := attribute.Find(fn.Attributes, "export")
+	if !ok || attribute.Validate(export, attribute.Function) != nil {
+		return diags
+	}
+	name, _ := attribute.StringArg(export, 0)
+	first, taken := exporters[name]
+	if !taken {
+		exporters[name] = fn
+		return diags
+	}
+
+	diag := diagnostic.New(export.Token.Pos, codeExportTwice, name, first.Name.Value)
+	diag.Notes = []diagnostic.Note{{Pos: first.Name.Token.Pos, Length: len(first.Name.Value), Message: "the first function exported as " + name}}
+	return append(diags, diag)
+}
+
+// cases can't fall through, so a case with no statements before another is most likely written expecting to
+func checkSwitch(stmt *ast.SwitchStmt, diags []diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	seen := make(map[string]ast.Expr) // by ExactString, which quotes strings so they never clash with numbers
+	var defaultCase *ast.SwitchCase
+
+	for i, c := range stmt.Cases {
+		if c.Values == nil {
+			if defaultCase != nil {
+				diag := diagnostic.New(c.Token.Pos, codeDefaultTwice, defaultCase.Token.Pos)
+				diag.Notes = []diagnostic.Note{{Pos: defaultCase.Token.Pos, Length: len("default"), Message: "the first default"}}
+				diags = append(diags, diag)
+			}
+			defaultCase = c
+		}
+		if len(c.Body.Stmts) == 0 && i < len(stmt.Cases)-1 {
+			diags = append(diags, diagnostic.New(c.Token.Pos, codeEmptyCase, strings.TrimSuffix(c.String(), ": ")))
+		}
+
+		for _, value := range c.Values {
+			v, ok := caseValue(value)
+			if !ok {
+				diags = append(diags, diagnostic.New(position(value, c), codeCaseValue, value.String()))
+				continue
+			}
+			if first, ok := seen[v.ExactString()]; ok {
+				diag := diagnostic.New(position(value, c), codeCaseTwice, value.String(), position(first, c))
+				diag.Notes = []diagnostic.Note{{Pos: position(first, c), Message: "the first case with this value"}}
+				diags = append(diags, diag)
+				continue
+			}
+			seen[v.ExactString()] = value
+		}
+	}
+	return diags
+}
+
+// the value of an integer or string literal, possibly negated
+func caseValue(expr ast.Expr) (constant.Value, bool) {
+	switch e := expr.(type) {
+	case *ast.NumberLiteral:
+		if n, exact := constant.Int64Val(constant.ToInt(e.Value)); exact {
+			return constant.MakeInt64(n), true
+		}
+	case *ast.StringLiteral:
+		return constant.MakeString(e.Value), true
+	case *ast.PrefixExpr:
+		if number, ok := e.Right.(*ast.NumberLiteral); ok && e.Operator == ast.OpMinus {
+			if n, exact := constant.Int64Val(constant.ToInt(number.Value)); exact {
+				return constant.MakeInt64(-n), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// where a case value starts, falling back on its case for nodes without a token of their own
+func position(value ast.Expr, c *ast.SwitchCase) token.Position {
+	switch v := value.(type) {
+	case *ast.Identifier:
+		return v.Token.Pos
+	case *ast.NumberLiteral:
+		return v.Token.Pos
+	case *ast.StringLiteral:
+		return v.Token.Pos
+	case *ast.PrefixExpr:
+		return v.Token.Pos
+	}
+	return c.Token.Pos
+}
+
+// the index takes the first plain assignment to a name as its declaration so tools keep working on such
+// programs, that is the case reported as undeclared here along with names that resolve to nothing at all
+func checkAssign(ix *index.Index, target *ast.Identifier, diags []diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	sym, ok := ix.Lookup(target)
+	switch {
+	case !ok || sym.Def == target:
+		diag := diagnostic.New(target.Token.Pos, codeUndeclared, target.Value)
+		diag.Length = len(target.Value)
+		return append(diags, diag)
+	case sym.Kind == index.Const:
+		diag := diagnostic.New(target.Token.Pos, codeAssignConst, target.Value)
+		diag.Length = len(target.Value)
+		diag.Notes = []diagnostic.Note{{Pos: sym.Def.Token.Pos, Length: len(sym.Name), Message: "the constant is declared here"}}
+		return append(diags, diag)
+	}
+	return diags
+}
@@ -0,0 +1,96 @@
+package token
+
+import (
+	"sort"
+	"sync"
+)
+
+// A File records a source file's name and where each of its lines starts, so byte offsets
+// can be turned back into positions after the tokens are gone
+type File struct {
+	Name  string
+	Size  int
+	lines []int // offset of the first character of each line
+}
+
+func NewFile(name string, src []byte) *File {
+	lines := []int{0}
+	for offset, char := range src {
+		if char == '\n' {
+			lines = append(lines, offset+1)
+		}
+	}
+	return &File{Name: name, Size: len(src), lines: lines}
+}
+
+func (f *File) LineCount() int {
+	return len(f.lines)
+}
+
+// LineStart returns the offset of the first character of line, which starts at 1
+func (f *File) LineStart(line int) int {
+	return f.lines[line-1]
+}
+
+// Position converts a byte offset into a position in this file
+func (f *File) Position(offset int) Position {
+	line := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > offset })
+	return Position{Filename: f.Name, Offset: offset, Line: line, Column: offset - f.lines[line-1] + 1}
+}
+
+// A FileSet holds the files of a program that spans several, so a position from any of them can be traced back
+// to its file. Positions carry the name of their file, which is what they are looked up by. A FileSet is safe
+// for concurrent use, a language server adds files while others are being read.
+type FileSet struct {
+	mu     sync.RWMutex
+	files  []*File // in the order they were first added
+	byName map[string]*File
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{byName: make(map[string]*File)}
+}
+
+// AddFile records src under name and returns its File. Adding a name again replaces the file, keeping its
+// place in the order, so edited files can be added afresh.
+func (s *FileSet) AddFile(name string, src []byte) *File {
+	file := NewFile(name, src)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.byName[name]; ok {
+		for i := range s.files {
+			if s.files[i] == old {
+				s.files[i] = file
+			}
+		}
+	} else {
+		s.files = append(s.files, file)
+	}
+	s.byName[name] = file
+	return file
+}
+
+// File returns the file named name, or nil when there isn't one
+func (s *FileSet) File(name string) *File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byName[name]
+}
+
+// Files returns every file in the set, in the order they were first added
+func (s *FileSet) Files() []*File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*File(nil), s.files...)
+}
+
+// Position works out the line and column of pos from its Filename and Offset, for positions that only had
+// an offset filled in. It reports false when the set has no such file or the offset is past its end.
+func (s *FileSet) Position(pos Position) (Position, bool) {
+	file := s.File(pos.Filename)
+	if file == nil || pos.Offset < 0 || pos.Offset > file.Size {
+		return pos, false
+	}
+	return file.Position(pos.Offset), true
+}
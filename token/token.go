@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 type TokenType string
 
 const (
@@ -7,10 +9,38 @@ const (
 	Identifier TokenType = "Identifier"
 	Number     TokenType = "Number"
 	String     TokenType = "String"
+	RawString  TokenType = "RawString" // `...`, no interpolation and may span lines
+	Bytes      TokenType = "Bytes"     // b"...", the literal keeps its escapes undecoded
+	Heredoc    TokenType = "Heredoc"   // <<<TAG, the literal is the lines up to the one holding just TAG
 
 	// Keywords
-	Def    TokenType = "Def"
-	Extern TokenType = "Extern"
+	Def     TokenType = "Def"
+	Extern  TokenType = "Extern"
+	Struct  TokenType = "Struct"
+	Match   TokenType = "Match"
+	Enum    TokenType = "Enum"
+	Nil     TokenType = "Nil"
+	In      TokenType = "In"
+	Const   TokenType = "Const"
+	Let     TokenType = "Let"
+	As      TokenType = "As"
+	Try     TokenType = "Try"
+	Catch   TokenType = "Catch"
+	True    TokenType = "True"
+	False   TokenType = "False"
+	Switch  TokenType = "Switch"
+	Case    TokenType = "Case"
+	Default TokenType = "Default"
+
+	// reserved for statements the language doesn't have yet
+	If     TokenType = "If"
+	Else   TokenType = "Else"
+	For    TokenType = "For"
+	While  TokenType = "While"
+	Return TokenType = "Return"
+	Import TokenType = "Import"
+
+	StaticAssert TokenType = "StaticAssert"
 
 	// Grouping
 	LeftParen          TokenType = "LeftParen"
@@ -20,9 +50,15 @@ const (
 	LeftSquareBracket  TokenType = "LeftSquareBracket"
 	RightSquareBracket TokenType = "RightSquareBracket"
 	Semicolon          TokenType = "Semicolon"
+	Newline            TokenType = "Newline" // only with lexer.Options.Newlines, ends a statement like a semicolon
 	Comma              TokenType = "Comma"
 	Colon              TokenType = "Colon"
 	Dot                TokenType = "Dot"
+	Ellipsis           TokenType = "Ellipsis"
+	Range              TokenType = "Range"          // ..
+	RangeInclusive     TokenType = "RangeInclusive" // ..=
+	Question           TokenType = "Question"
+	At                 TokenType = "At" // starts an attribute, like @inline
 
 	// Symbols
 	Plus        TokenType = "Plus"
@@ -34,6 +70,7 @@ const (
 	GreaterThan TokenType = "GreaterThan"
 	LessThan    TokenType = "LessThan"
 	Bang        TokenType = "Bang"
+	Backslash   TokenType = "Backslash"
 
 	// Multi char symbols
 	EqualTo            TokenType = "Equality"
@@ -42,14 +79,41 @@ const (
 	NotEqualTo         TokenType = "NotEqual"
 	And                TokenType = "And"
 	Or                 TokenType = "Or"
+	Arrow              TokenType = "Arrow"
+	FatArrow           TokenType = "FatArrow"
+	Power              TokenType = "Power"
+	PlusAssign         TokenType = "PlusAssign"
+	MinusAssign        TokenType = "MinusAssign"
+	StarAssign         TokenType = "StarAssign"
+	SlashAssign        TokenType = "SlashAssign"
+	ModuloAssign       TokenType = "ModuloAssign"
+	Increment          TokenType = "Increment"
+	Decrement          TokenType = "Decrement"
 
+	Comment TokenType = "Comment"
 	EOF     TokenType = "EOF" // End of File
 	Illegal TokenType = "Illegal"
 )
 
+// Position of the first character of a token, Line and Column start at 1
+type Position struct {
+	Filename string // empty when the source didn't come from a named file
+	Offset   int
+	Line     int
+	Column   int
+}
+
+func (p Position) String() string {
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 type Token struct {
 	Literal string
 	Type    TokenType
+	Pos     Position
 }
 
 func MakeToken(Type TokenType, char byte) Token {
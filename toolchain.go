@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"llvm-lang/toolchain"
+	"os"
+)
+
+// llvm-lang toolchain [-clang path] [-lld path] [-llc path] [-json]
+func runToolchain(args []string) int {
+	flags := flag.NewFlagSet("toolchain", flag.ExitOnError)
+	overrides := toolchain.Overrides{}
+	for _, name := range []string{"clang", "lld", "llc"} {
+		name := name
+		flags.Func(name, "use this "+name+" instead of looking for one on PATH", func(path string) error {
+			overrides[name] = path
+			return nil
+		})
+	}
+	asJSON := flags.Bool("json", false, "print the tools found as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: llvm-lang toolchain [-clang path] [-lld path] [-llc path] [-json]\n\nFinds the LLVM tools the compiler drives and prints where each is and its version.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	tools, err := toolchain.Discover(overrides)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(tools); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	for _, tool := range []*toolchain.Tool{tools.Clang, tools.LLD, tools.LLC} {
+		fmt.Printf("%s\t%s\t%s\n", tool.Name, tool.Version, tool.Path)
+	}
+	return 0
+}
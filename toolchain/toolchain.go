@@ -0,0 +1,134 @@
+package toolchain
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Oldest LLVM major version we are willing to drive
+const MinLLVMVersion = 14
+
+type Tool struct {
+	Name    string `json:"name"` // logical name, e.g. "clang"
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+type Toolchain struct {
+	Clang *Tool `json:"clang"`
+	LLD   *Tool `json:"lld"`
+	LLC   *Tool `json:"llc"`
+}
+
+// Overrides maps a logical tool name ("clang", "lld", "llc") to an explicit path,
+// normally read out of a project manifest
+type Overrides map[string]string
+
+var ErrNotFound = errors.New("tool not found")
+
+// binaries probed on PATH for each logical tool, in order of preference
+var candidates = map[string][]string{
+	"clang": {"clang"},
+	"lld":   {"ld.lld", "lld"},
+	"llc":   {"llc"},
+}
+
+// clang and llc print "version 17.0.6", ld.lld prints "LLD 17.0.6 (compatible with GNU linkers)"
+var versionPattern = regexp.MustCompile(`(?:version|LLD) (\d+)\.(\d+)(?:\.(\d+))?`)
+
+// Discover locates every tool the driver needs, returning the first failure
+func Discover(overrides Overrides) (*Toolchain, error) {
+	clang, err := Find("clang", overrides["clang"])
+	if err != nil {
+		return nil, err
+	}
+
+	lld, err := Find("lld", overrides["lld"])
+	if err != nil {
+		return nil, err
+	}
+
+	llc, err := Find("llc", overrides["llc"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Toolchain{Clang: clang, LLD: lld, LLC: llc}, nil
+}
+
+// Find resolves a single tool, preferring override when it is set
+func Find(name string, override string) (*Tool, error) {
+	path, err := locate(name, override)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := probeVersion(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s at %s: %w", name, path, err)
+	}
+
+	if major, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0]); major < MinLLVMVersion {
+		return nil, fmt.Errorf("%s at %s is version %s, need LLVM %d or newer", name, path, version, MinLLVMVersion)
+	}
+
+	return &Tool{Name: name, Path: path, Version: version}, nil
+}
+
+func locate(name string, override string) (string, error) {
+	if override != "" {
+		info, err := os.Stat(override)
+		if err != nil {
+			return "", fmt.Errorf("%s override %s: %w", name, override, ErrNotFound)
+		}
+		if info.IsDir() || info.Mode()&0111 == 0 {
+			return "", fmt.Errorf("%s override %s is not an executable file", name, override)
+		}
+		return override, nil
+	}
+
+	names, ok := candidates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", name)
+	}
+
+	for _, bin := range names {
+		if path, err := exec.LookPath(bin); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s (tried %s on PATH): %w; install LLVM or set its path in the manifest",
+		name, strings.Join(names, ", "), ErrNotFound)
+}
+
+// runs `<path> --version` and pulls the first dotted version number out of it
+func probeVersion(path string) (string, error) {
+	out, err := exec.Command(path, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("could not run --version: %w", err)
+	}
+
+	match := versionPattern.FindStringSubmatch(string(out))
+	if match == nil {
+		return "", fmt.Errorf("could not find a version in %q", firstLine(string(out)))
+	}
+
+	version := match[1] + "." + match[2]
+	if match[3] != "" {
+		version += "." + match[3]
+	}
+	return version, nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,172 @@
+package toolchain
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// what each tool prints for --version, as LLVM 17 does
+const (
+	clangVersion = "clang version 17.0.6\nTarget: x86_64-pc-linux-gnu\nThread model: posix\n"
+	lldVersion   = "LLD 17.0.6 (compatible with GNU linkers)\n"
+	llcVersion   = "LLVM (http://llvm.org/):\n  LLVM version 17.0.6\n  Optimized build.\n"
+)
+
+// writes a shell script called name into dir that prints output and exits with status, and returns its path
+func fakeTool(t *testing.T, dir string, name string, output string, status int) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\nprintf '%s' '" + strings.ReplaceAll(output, "'", `'\''`) + "'\nexit " + strconv.Itoa(status) + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// a directory holding the given tools, made the whole of PATH for the test
+func fakePath(t *testing.T, tools map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, output := range tools {
+		fakeTool(t, dir, name, output, 0)
+	}
+	t.Setenv("PATH", dir)
+	return dir
+}
+
+func TestDiscover(t *testing.T) {
+	dir := fakePath(t, map[string]string{"clang": clangVersion, "ld.lld": lldVersion, "llc": llcVersion})
+
+	tools, err := Discover(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []Tool{
+		{Name: "clang", Path: filepath.Join(dir, "clang"), Version: "17.0.6"},
+		{Name: "lld", Path: filepath.Join(dir, "ld.lld"), Version: "17.0.6"},
+		{Name: "llc", Path: filepath.Join(dir, "llc"), Version: "17.0.6"},
+	} {
+		got := map[string]*Tool{"clang": tools.Clang, "lld": tools.LLD, "llc": tools.LLC}[want.Name]
+		if *got != want {
+			t.Errorf("got %+v, want %+v", *got, want)
+		}
+	}
+}
+
+func TestFindPrefersEarlierCandidates(t *testing.T) {
+	dir := fakePath(t, map[string]string{"ld.lld": lldVersion, "lld": "LLD 15.0.0\n"})
+
+	tool, err := Find("lld", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool.Path != filepath.Join(dir, "ld.lld") {
+		t.Errorf("found %s, want ld.lld", tool.Path)
+	}
+
+	os.Remove(filepath.Join(dir, "ld.lld"))
+	tool, err = Find("lld", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool.Path != filepath.Join(dir, "lld") || tool.Version != "15.0.0" {
+		t.Errorf("found %s version %s, want lld version 15.0.0", tool.Path, tool.Version)
+	}
+}
+
+func TestFindOverride(t *testing.T) {
+	fakePath(t, map[string]string{"clang": clangVersion})
+	override := fakeTool(t, t.TempDir(), "clang-18", "Ubuntu clang version 18.1.3 (1ubuntu1)\n", 0)
+
+	tool, err := Find("clang", override)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tool.Path != override || tool.Version != "18.1.3" {
+		t.Errorf("found %s version %s, want the override at version 18.1.3", tool.Path, tool.Version)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		tool     string
+		override string
+		path     map[string]string // the fake tools on PATH
+		notFound bool              // whether the error wraps ErrNotFound
+		want     string            // in the error
+	}{
+		{
+			name:     "missing",
+			tool:     "lld",
+			notFound: true,
+			want:     "lld (tried ld.lld, lld on PATH): tool not found; install LLVM or set its path in the manifest",
+		},
+		{
+			name: "too old",
+			tool: "clang",
+			path: map[string]string{"clang": "clang version 11.1.0\n"},
+			want: "is version 11.1.0, need LLVM 14 or newer",
+		},
+		{
+			name: "no version",
+			tool: "llc",
+			path: map[string]string{"llc": "llc, a code generator\nsecond line\n"},
+			want: `could not find a version in "llc, a code generator"`,
+		},
+		{
+			name:     "missing override",
+			tool:     "clang",
+			override: filepath.Join(dir, "nowhere", "clang"),
+			notFound: true,
+			want:     "clang override " + filepath.Join(dir, "nowhere", "clang") + ": tool not found",
+		},
+		{
+			name:     "override is a directory",
+			tool:     "clang",
+			override: dir,
+			want:     "clang override " + dir + " is not an executable file",
+		},
+		{
+			name: "unknown tool",
+			tool: "gcc",
+			want: `unknown tool "gcc"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakePath(t, test.path)
+
+			_, err := Find(test.tool, test.override)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("error %q doesn't say %q", err, test.want)
+			}
+			if errors.Is(err, ErrNotFound) != test.notFound {
+				t.Errorf("errors.Is(%q, ErrNotFound) is %v", err, !test.notFound)
+			}
+		})
+	}
+}
+
+func TestFindFailingTool(t *testing.T) {
+	dir := fakePath(t, nil)
+	fakeTool(t, dir, "llc", "llc: error while loading shared libraries\n", 127)
+
+	_, err := Find("llc", "")
+	if err == nil || !strings.Contains(err.Error(), "could not run --version") {
+		t.Errorf("got error %v, want one saying --version could not run", err)
+	}
+}
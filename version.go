@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"llvm-lang/buildinfo"
+	"os"
+)
+
+// llvm-lang version [-json]
+func runVersion(args []string) int {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the version, commit, Go version and language features as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: llvm-lang version [-json]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	info := buildinfo.Get()
+	if !*asJSON {
+		fmt.Println(info)
+		return 0
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(info); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
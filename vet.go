@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"llvm-lang/diagnostic"
+	"llvm-lang/driver"
+	"llvm-lang/lint"
+	"os"
+	"strings"
+)
+
+// llvm-lang vet [-disable rules] [-list] [-diag-format format] [-fix] [file]
+func runVet(args []string) int {
+	flags := flag.NewFlagSet("vet", flag.ExitOnError)
+	disable := flags.String("disable", "", "comma separated names of rules not to run")
+	list := flags.Bool("list", false, "list the available rules and exit")
+	diagFormat := flags.String("diag-format", diagnostic.FormatText, "how to print diagnostics: text, json or sarif")
+	fix := flags.Bool("fix", false, "make the suggested fixes, rewriting the file, or printing the result when reading stdin")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: llvm-lang vet [-disable rules] [-list] [-diag-format format] [-fix] [file]\n\nReads from stdin when no file is given.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if !checkDiagFormat(*diagFormat) {
+		return 2
+	}
+
+	if *list {
+		for _, rule := range lint.Rules {
+			fmt.Printf("%s\t%s\t%s\n", rule.Code, rule.Name, rule.Doc)
+		}
+		return 0
+	}
+
+	config := lint.Config{Disabled: make(map[string]bool)}
+	for _, name := range strings.Split(*disable, ",") {
+		if name == "" {
+			continue
+		}
+		if _, ok := lint.Lookup(name); !ok {
+			fmt.Fprintf(os.Stderr, "unknown rule %q, see -list\n", name)
+			return 2
+		}
+		config.Disabled[name] = true
+	}
+
+	source, err := readSource(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	name := flags.Arg(0)
+	if name == "" || name == "-" {
+		name = "<stdin>"
+	}
+
+	result := driver.Check([]byte(source), driver.Options{Filename: name, Lint: true, Config: config})
+	diags := result.Diagnostics
+	if *fix {
+		if diags, err = applyFixes(flags.Arg(0), source, diags); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	report(source, diags, nil, false, diagnostic.DefaultLocale, *diagFormat)
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}